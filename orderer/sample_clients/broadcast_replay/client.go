@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/sample_clients/submit"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// defaultMaxEnvelopeSize is the default AbsoluteMaxBytes of configtxgen,
+// the largest envelope an orderer accepts unless configured otherwise.
+const defaultMaxEnvelopeSize = 10 * 1024 * 1024
+
+// readEnvelopes loads a capture file made of marshaled envelopes, each one
+// preceded by its length encoded as an 8-byte big-endian unsigned integer.
+// A length above maxSize fails, as the file is then most likely corrupt.
+func readEnvelopes(path string, maxSize uint64) ([]*cb.Envelope, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var envs []*cb.Envelope
+	for {
+		var size uint64
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			if err == io.EOF {
+				return envs, nil
+			}
+			return nil, fmt.Errorf("error reading length of envelope %d: %s", len(envs), err)
+		}
+		if size > maxSize {
+			return nil, fmt.Errorf("envelope %d is %d bytes long, more than the maximum of %d", len(envs), size, maxSize)
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("error reading envelope %d: %s", len(envs), err)
+		}
+		env := &cb.Envelope{}
+		if err := proto.Unmarshal(buf, env); err != nil {
+			return nil, fmt.Errorf("error unmarshaling envelope %d: %s", len(envs), err)
+		}
+		envs = append(envs, env)
+	}
+}
+
+// replay submits every envelope whose index i satisfies i % workers == worker
+// on its own Broadcast stream, waiting for the status of each envelope before
+// sending the next one.
+func replay(conn *grpc.ClientConn, envs []*cb.Envelope, worker, workers int, delay time.Duration) {
+	client, err := ab.NewAtomicBroadcastClient(conn).Broadcast(context.TODO())
+	if err != nil {
+		fmt.Printf("Worker %d: error connecting: %s\n", worker, err)
+		return
+	}
+	defer client.CloseSend()
+
+	for i := worker; i < len(envs); i += workers {
+		if err := client.Send(envs[i]); err != nil {
+			fmt.Printf("Envelope %d: error sending: %s\n", i, err)
+			return
+		}
+		resp, err := client.Recv()
+		if err != nil {
+			fmt.Printf("Envelope %d: error receiving status: %s\n", i, err)
+			return
+		}
+		if resp.Status != cb.Status_SUCCESS {
+			fmt.Printf("Envelope %d: %s - %s\n", i, resp.Status, resp.Info)
+		} else {
+			fmt.Printf("Envelope %d: %s\n", i, resp.Status)
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+}
+
+func main() {
+	config, err := config.Load()
+	if err != nil {
+		fmt.Println("failed to load config:", err)
+		os.Exit(1)
+	}
+
+	var serverAddr string
+	var inputFile string
+	var goroutines int
+	var delay time.Duration
+	var tlsEnabled bool
+	var caFile string
+	var serverName string
+	var maxSize uint64
+
+	var defaultCAFile string
+	if len(config.General.TLS.RootCAs) > 0 {
+		defaultCAFile = config.General.TLS.RootCAs[0]
+	}
+
+	flag.StringVar(&serverAddr, "server", fmt.Sprintf("%s:%d", config.General.ListenAddress, config.General.ListenPort), "The RPC server to connect to.")
+	flag.StringVar(&inputFile, "file", "", "The file of length-prefixed marshaled envelopes to replay.")
+	flag.Uint64Var(&maxSize, "max-size", defaultMaxEnvelopeSize, "The largest envelope accepted from the file, in bytes.")
+	flag.IntVar(&goroutines, "goroutines", 1, "The number of concurrent Broadcast streams to replay the envelopes on.")
+	flag.DurationVar(&delay, "delay", 0, "The delay between two consecutive sends on the same stream.")
+	flag.BoolVar(&tlsEnabled, "tls", config.General.TLS.Enabled, "Use TLS when connecting to the RPC server.")
+	flag.StringVar(&caFile, "cafile", defaultCAFile, "The PEM-encoded root certificate used to verify the RPC server when TLS is enabled.")
+	flag.StringVar(&serverName, "servername", "", "Override the server name expected in the TLS certificate of the RPC server.")
+	flag.Parse()
+
+	if inputFile == "" || goroutines < 1 {
+		fmt.Println("An input file and a positive number of goroutines are required.")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	envs, err := readEnvelopes(inputFile, maxSize)
+	if err != nil {
+		fmt.Println("Error loading envelopes:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Loaded %d envelopes from %s\n", len(envs), inputFile)
+
+	opts, err := submit.DialOptions(tlsEnabled, caFile, serverName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	conn, err := grpc.Dial(serverAddr, opts...)
+	if err != nil {
+		fmt.Println("Error connecting:", err)
+		return
+	}
+	defer conn.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(worker int) {
+			defer wg.Done()
+			replay(conn, envs, worker, goroutines, delay)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/configtx"
+	"github.com/hyperledger/fabric/common/tools/protolator"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// unsupportedTypeError reports an envelope whose header type carries no
+// config update.
+type unsupportedTypeError struct {
+	headerType cb.HeaderType
+}
+
+func (e unsupportedTypeError) Error() string {
+	return fmt.Sprintf("envelope of type %s carries no config update", e.headerType)
+}
+
+// malformedPayloadError reports an envelope of a type carrying a config
+// update whose payload data cannot be unmarshaled.
+type malformedPayloadError struct {
+	headerType cb.HeaderType
+	err        error
+}
+
+func (e malformedPayloadError) Error() string {
+	return fmt.Sprintf("malformed %s payload: %s", e.headerType, e.err)
+}
+
+// retrieveLastUpdate returns the config update envelope carried by a config
+// related envelope: the envelope itself for a CONFIG_UPDATE, the last update
+// of a CONFIG, and the last update of the wrapped CONFIG for an
+// ORDERER_TRANSACTION. Envelopes of any other type fail with an
+// unsupportedTypeError, and undecodable payload data with a
+// malformedPayloadError.
+func retrieveLastUpdate(env *cb.Envelope) (*cb.Envelope, error) {
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, fmt.Errorf("envelope has no header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	headerType := cb.HeaderType(chdr.Type)
+	switch headerType {
+	case cb.HeaderType_CONFIG_UPDATE:
+		return env, nil
+	case cb.HeaderType_CONFIG:
+		configEnv, err := configtx.UnmarshalConfigEnvelope(payload.Data)
+		if err != nil {
+			return nil, malformedPayloadError{headerType: headerType, err: err}
+		}
+		return configEnv.LastUpdate, nil
+	case cb.HeaderType_ORDERER_TRANSACTION:
+		inner, err := utils.UnmarshalEnvelope(payload.Data)
+		if err != nil {
+			return nil, malformedPayloadError{headerType: headerType, err: err}
+		}
+		return retrieveLastUpdate(inner)
+	default:
+		return nil, unsupportedTypeError{headerType: headerType}
+	}
+}
+
+func printSection(title string, msg proto.Message) {
+	fmt.Println(title)
+	if err := protolator.DeepMarshalJSON(os.Stdout, msg); err != nil {
+		fmt.Printf("  Error pretty printing %s: %s\n", title, err)
+	}
+}
+
+func main() {
+	var inputFile string
+
+	flag.StringVar(&inputFile, "file", "", "The file holding a marshaled envelope.")
+	flag.Parse()
+
+	if inputFile == "" {
+		fmt.Println("An input file is required.")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	envBytes, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		fmt.Println("Error reading envelope:", err)
+		os.Exit(1)
+	}
+	env, err := utils.UnmarshalEnvelope(envBytes)
+	if err != nil {
+		fmt.Println("Error unmarshaling envelope:", err)
+		os.Exit(1)
+	}
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		fmt.Println("Error unmarshaling payload:", err)
+		os.Exit(1)
+	}
+	if payload.Header == nil {
+		fmt.Println("Envelope has no header")
+		os.Exit(1)
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		fmt.Println("Error unmarshaling channel header:", err)
+		os.Exit(1)
+	}
+
+	printSection("Channel header:", chdr)
+	printSection("Payload:", payload)
+
+	switch cb.HeaderType(chdr.Type) {
+	case cb.HeaderType_CONFIG, cb.HeaderType_ORDERER_TRANSACTION:
+		lastUpdate, err := retrieveLastUpdate(env)
+		if err != nil {
+			fmt.Println("Error retrieving last update:", err)
+			os.Exit(1)
+		}
+		printSection("Last update:", lastUpdate)
+	}
+}
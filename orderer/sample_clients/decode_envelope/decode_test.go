@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func envelope(headerType cb.HeaderType, data []byte) *cb.Envelope {
+	return &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+		Header: &cb.Header{ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{Type: int32(headerType), ChannelId: "foo"})},
+		Data:   data,
+	})}
+}
+
+func TestRetrieveLastUpdate(t *testing.T) {
+	update := envelope(cb.HeaderType_CONFIG_UPDATE, []byte("update"))
+	config := envelope(cb.HeaderType_CONFIG, utils.MarshalOrPanic(&cb.ConfigEnvelope{Config: &cb.Config{}, LastUpdate: update}))
+
+	for _, test := range []struct {
+		name        string
+		env         *cb.Envelope
+		lastUpdate  *cb.Envelope
+		unsupported bool
+		malformed   bool
+	}{
+		{name: "config update", env: update, lastUpdate: update},
+		{name: "config", env: config, lastUpdate: update},
+		{name: "orderer transaction", env: envelope(cb.HeaderType_ORDERER_TRANSACTION, utils.MarshalOrPanic(config)), lastUpdate: update},
+		{name: "endorser transaction", env: envelope(cb.HeaderType_ENDORSER_TRANSACTION, nil), unsupported: true},
+		{name: "orderer transaction wrapping a message", env: envelope(cb.HeaderType_ORDERER_TRANSACTION, utils.MarshalOrPanic(envelope(cb.HeaderType_MESSAGE, nil))), unsupported: true},
+		{name: "malformed config", env: envelope(cb.HeaderType_CONFIG, []byte("garbage")), malformed: true},
+		{name: "malformed orderer transaction", env: envelope(cb.HeaderType_ORDERER_TRANSACTION, []byte("garbage")), malformed: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			lastUpdate, err := retrieveLastUpdate(test.env)
+			_, unsupported := err.(unsupportedTypeError)
+			_, malformed := err.(malformedPayloadError)
+			assert.Equal(t, test.unsupported, unsupported, "unexpected error: %v", err)
+			assert.Equal(t, test.malformed, malformed, "unexpected error: %v", err)
+			if test.lastUpdate != nil {
+				assert.NoError(t, err)
+				assert.True(t, proto.Equal(test.lastUpdate, lastUpdate))
+			}
+		})
+	}
+}
+
+func TestRetrieveLastUpdateNoHeader(t *testing.T) {
+	_, err := retrieveLastUpdate(&cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{})})
+	assert.EqualError(t, err, "envelope has no header")
+}
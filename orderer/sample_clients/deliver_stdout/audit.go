@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"google.golang.org/grpc"
+)
+
+// auditResult is the outcome of one audit run, logged as a line of JSON.
+type auditResult struct {
+	Run      int       `json:"run"`
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Status   string    `json:"status"`
+	Result   string    `json:"result"`
+	Manifest manifest  `json:"manifest"`
+}
+
+// audit verifies the signatures, data hashes and hash chain of the whole
+// channel, from the genesis block up to the newest block at the start of
+// the run, once every interval. A run stopped short resumes from the
+// checkpoint, if set, on the next run; a complete run removes it so that the
+// next one starts over from the genesis block. Delivery is re-established
+// with redial whenever the stream fails. audit only returns once signatures
+// cannot be verified for lack of a usable MSP.
+func (r *deliverClient) audit(interval time.Duration, retries int, redial func() (*grpc.ClientConn, ab.AtomicBroadcast_DeliverClient, error)) {
+	for run := 1; ; run++ {
+		started := time.Now()
+		r.received, r.last = 0, 0
+		r.verifier.verified, r.verifier.failed = 0, 0
+		r.manifest = newManifestBuilder(r.channelID, r.hash)
+		r.checkpointValid = true
+
+		start := oldest
+		if r.checkpoint != "" {
+			if number, err := readCheckpoint(r.checkpoint); err == nil {
+				if resumed, ok, err := r.checkpointStart(number, newest); err == nil && ok {
+					fmt.Printf("Audit run %d resuming after block %d saved in checkpoint %s\n", run, number, r.checkpoint)
+					start = resumed
+				}
+			}
+		}
+
+		status := cb.Status_UNKNOWN
+		if err := r.request(start, newest); err != nil {
+			fmt.Println("Received error:", err)
+		} else {
+			status = r.readWithRetries(retries)
+		}
+
+		result := checkPass
+		m := r.manifest.manifest
+		if status != cb.Status_SUCCESS || m.Signatures == checkFail || m.DataHash == checkFail || m.HashChain == checkFail {
+			result = checkFail
+		}
+		if status == cb.Status_SUCCESS && r.checkpoint != "" {
+			if err := os.Remove(r.checkpoint); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("Error removing checkpoint %s: %s\n", r.checkpoint, err)
+			}
+		}
+		line, err := json.Marshal(&auditResult{
+			Run:      run,
+			Started:  started,
+			Duration: time.Since(started).String(),
+			Status:   status.String(),
+			Result:   result,
+			Manifest: m,
+		})
+		if err != nil {
+			fmt.Printf("Error serializing the result of audit run %d: %s\n", run, err)
+		} else {
+			fmt.Println(string(line))
+		}
+
+		if r.verifier.unavailable {
+			return
+		}
+		time.Sleep(time.Until(started.Add(interval)))
+
+		if status == cb.Status_UNKNOWN {
+			conn, client, err := redial()
+			if err != nil {
+				fmt.Println("Error reconnecting:", err)
+				continue
+			}
+			r.close()
+			r.conn, r.client = conn, client
+		}
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readCheckpoint returns the block number saved in the checkpoint file.
+func readCheckpoint(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt checkpoint %s: %s", path, err)
+	}
+	return number, nil
+}
+
+// writeCheckpoint saves the block number to the checkpoint file, replacing
+// it atomically so that an interruption never leaves a corrupt checkpoint.
+func writeCheckpoint(path string, number uint64) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(number, 10)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -7,10 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/localmsp"
@@ -18,12 +26,14 @@ import (
 	"github.com/hyperledger/fabric/common/tools/protolator"
 	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/sample_clients/submit"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
 	"github.com/hyperledger/fabric/protos/utils"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 var (
@@ -32,22 +42,98 @@ var (
 	maxStop = &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: math.MaxUint64}}}
 )
 
+// Exit codes of the client.
+const (
+	exitSuccess = iota
+	exitFailure
+	exitNotFound
+	exitConfigError
+)
+
+// singleServerFlags lists the flags which -servers does not apply, as they
+// act on the delivery from a single orderer.
+var singleServerFlags = []string{
+	"audit-interval", "check-last-config", "checkpoint", "config-dir", "config-only", "count-only",
+	"expect-tip", "expect-tip-hash", "expect-txs", "expect-txs-tolerance", "fields", "floor", "forward",
+	"genesis-hash", "hash", "manifest", "metadata-sizes", "monitor", "ndjson", "no-verify-count",
+	"prove-tx", "retries", "reverse", "timeout",
+}
+
 type deliverClient struct {
+	conn      *grpc.ClientConn
 	client    ab.AtomicBroadcast_DeliverClient
 	channelID string
 	signer    crypto.LocalSigner
 	quiet     bool
+	ndjson    bool
+	fields    *fieldSelector
+	records   io.Writer
+	behavior  ab.SeekInfo_SeekBehavior
+	verifier  *verifier
+	hashFunc  func([]byte) []byte
+	genesis   []byte
+	lastCfg   *lastConfigChecker
+	dump      bool
+	sizes     *metadataSizes
+	proveTx   string
+	proved    bool
+
+	// checkpoint is the file saving the number of the last block whose
+	// signatures were verified; it stops advancing after a failure.
+	checkpoint      string
+	checkpointValid bool
+
+	// manifest summarizes the delivered range into the manifestPath file.
+	manifest     *manifestBuilder
+	manifestPath string
+
+	received uint64
+	last     uint64
+
+	// configOnly skips every block but config blocks, whose numbers are
+	// collected and which are also saved to configDir if set.
+	configOnly   bool
+	configBlocks []uint64
+	configDir    string
+
+	// countOnly discards the delivered blocks after counting them and
+	// their transactions.
+	countOnly bool
+	txs       uint64
+
+	// tally checks the number of delivered transactions if set.
+	tally *txTally
+
+	// monitor alerts on the first block failing verification, which sets
+	// alerted and ends delivery.
+	monitor bool
+	alerted bool
+
+	// forwarder relays the blocks whose signatures were verified downstream.
+	forwarder *forwarder
+
+	// start and stop are the positions of the last seek request sent.
+	start *ab.SeekPosition
+	stop  *ab.SeekPosition
 }
 
 func newDeliverClient(client ab.AtomicBroadcast_DeliverClient, channelID string, signer crypto.LocalSigner, quiet bool) *deliverClient {
-	return &deliverClient{client: client, channelID: channelID, signer: signer, quiet: quiet}
+	return &deliverClient{client: client, channelID: channelID, signer: signer, quiet: quiet, records: os.Stdout}
+}
+
+// close ends the Deliver stream and tears down the connection.
+func (r *deliverClient) close() {
+	r.client.CloseSend()
+	if r.conn != nil {
+		r.conn.Close()
+	}
 }
 
 func (r *deliverClient) seekHelper(start *ab.SeekPosition, stop *ab.SeekPosition) *cb.Envelope {
 	env, err := utils.CreateSignedEnvelope(cb.HeaderType_DELIVER_SEEK_INFO, r.channelID, r.signer, &ab.SeekInfo{
 		Start:    start,
 		Stop:     stop,
-		Behavior: ab.SeekInfo_BLOCK_UNTIL_READY,
+		Behavior: r.behavior,
 	}, 0, 0)
 	if err != nil {
 		panic(err)
@@ -55,33 +141,232 @@ func (r *deliverClient) seekHelper(start *ab.SeekPosition, stop *ab.SeekPosition
 	return env
 }
 
+func specified(number uint64) *ab.SeekPosition {
+	return &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: number}}}
+}
+
+// request sends a seek request and remembers its positions so that it can
+// be resumed.
+func (r *deliverClient) request(start *ab.SeekPosition, stop *ab.SeekPosition) error {
+	r.start, r.stop = start, stop
+	return r.client.Send(r.seekHelper(start, stop))
+}
+
+// resume re-issues the last seek request, starting after the last block
+// received if any.
+func (r *deliverClient) resume() error {
+	start := r.start
+	if r.received > 0 {
+		start = specified(r.last + 1)
+	}
+	return r.request(start, r.stop)
+}
+
 func (r *deliverClient) seekOldest() error {
-	return r.client.Send(r.seekHelper(oldest, maxStop))
+	return r.request(oldest, maxStop)
 }
 
 func (r *deliverClient) seekNewest() error {
-	return r.client.Send(r.seekHelper(newest, maxStop))
+	return r.request(newest, maxStop)
 }
 
 func (r *deliverClient) seekSingle(blockNumber uint64) error {
-	specific := &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: blockNumber}}}
-	return r.client.Send(r.seekHelper(specific, specific))
+	return r.request(specified(blockNumber), specified(blockNumber))
+}
+
+func (r *deliverClient) seekRange(start uint64, stop uint64) error {
+	return r.request(specified(start), specified(stop))
+}
+
+// fetchBlock requests the single block at the given position and consumes
+// the status closing the request.
+func (r *deliverClient) fetchBlock(position *ab.SeekPosition) (*cb.Block, error) {
+	if err := r.client.Send(r.seekHelper(position, position)); err != nil {
+		return nil, err
+	}
+
+	var block *cb.Block
+	for {
+		msg, err := r.client.Recv()
+		if err != nil {
+			return nil, err
+		}
+		switch t := msg.Type.(type) {
+		case *ab.DeliverResponse_Block:
+			if err := checkBlock(t.Block); err != nil {
+				return nil, fmt.Errorf("received a malformed block: %s", err)
+			}
+			block = t.Block
+		case *ab.DeliverResponse_Status:
+			if t.Status != cb.Status_SUCCESS || block == nil {
+				return nil, fmt.Errorf("fetching block failed with status %s", t.Status)
+			}
+			return block, nil
+		}
+	}
+}
+
+// newestBlockNumber probes the current height of the channel by requesting
+// the newest block only.
+func (r *deliverClient) newestBlockNumber() (uint64, error) {
+	block, err := r.fetchBlock(newest)
+	if err != nil {
+		return 0, err
+	}
+	return block.Header.Number, nil
+}
+
+// fetchLastConfig retrieves the config block the newest block points to.
+func (r *deliverClient) fetchLastConfig() (*cb.Block, error) {
+	block, err := r.fetchBlock(newest)
+	if err != nil {
+		return nil, err
+	}
+	index, err := lastConfigIndex(block)
+	if err != nil {
+		return nil, err
+	}
+	return r.fetchBlock(specified(index))
+}
+
+// tailPositions returns the positions of the last n blocks of the channel,
+// or of all of them if the channel holds fewer than n blocks.
+func (r *deliverClient) tailPositions(n uint64) (*ab.SeekPosition, *ab.SeekPosition, error) {
+	newestNumber, err := r.newestBlockNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	start := uint64(0)
+	if newestNumber+1 > n {
+		start = newestNumber + 1 - n
+	} else {
+		fmt.Printf("Channel holds only %d blocks\n", newestNumber+1)
+	}
+	return specified(start), specified(newestNumber), nil
+}
+
+// seekTail requests the last n blocks of the channel, or all of them if the
+// channel holds fewer than n blocks.
+func (r *deliverClient) seekTail(n uint64) error {
+	start, stop, err := r.tailPositions(n)
+	if err != nil {
+		return err
+	}
+	return r.request(start, stop)
+}
+
+// checkpointStart returns the start of a request resuming after the block
+// saved in a checkpoint, and false if that block already ends the range
+// which stops at stop.
+func (r *deliverClient) checkpointStart(number uint64, stop *ab.SeekPosition) (*ab.SeekPosition, bool, error) {
+	var last uint64
+	switch t := stop.Type.(type) {
+	case *ab.SeekPosition_Oldest:
+		last = 0
+	case *ab.SeekPosition_Newest:
+		newestNumber, err := r.newestBlockNumber()
+		if err != nil {
+			return nil, false, err
+		}
+		last = newestNumber
+	case *ab.SeekPosition_Specified:
+		last = t.Specified.Number
+	}
+	if number >= last {
+		return nil, false, nil
+	}
+	return specified(number + 1), true, nil
 }
 
-func (r *deliverClient) readUntilClose() {
+// readUntilClose prints the delivered blocks until the orderer closes the
+// request with a status, which is returned. UNKNOWN is returned if the
+// stream itself failed, or if the genesis block does not match the expected
+// hash.
+func (r *deliverClient) readUntilClose() cb.Status {
+	if r.countOnly {
+		return r.countUntilClose()
+	}
+
+	defer func() {
+		if r.fields != nil {
+			r.fields.report(r.received)
+		}
+		if r.lastCfg != nil {
+			fmt.Printf("Found %d LAST_CONFIG inconsistencies\n", r.lastCfg.inconsistencies)
+		}
+		if r.configOnly {
+			fmt.Printf("Found %d config blocks: %v\n", len(r.configBlocks), r.configBlocks)
+		}
+		if r.manifest != nil && r.manifestPath != "" {
+			if err := r.manifest.write(r.manifestPath); err != nil {
+				fmt.Printf("Error writing manifest %s: %s\n", r.manifestPath, err)
+			}
+		}
+		if r.proveTx != "" && !r.proved {
+			fmt.Printf("Transaction %s not found in the %d blocks received\n", r.proveTx, r.received)
+		}
+		if r.sizes != nil {
+			r.sizes.report()
+		}
+		if r.verifier != nil {
+			fmt.Printf("Received %d blocks, verified %d (%d failed)\n", r.received, r.verifier.verified, r.verifier.failed)
+		}
+	}()
+
 	for {
 		msg, err := r.client.Recv()
 		if err != nil {
 			fmt.Println("Error receiving:", err)
-			return
+			return cb.Status_UNKNOWN
 		}
 
 		switch t := msg.Type.(type) {
 		case *ab.DeliverResponse_Status:
-			fmt.Println("Got status ", t)
-			return
+			fmt.Println(r.describeStatus(t.Status))
+			return t.Status
 		case *ab.DeliverResponse_Block:
-			if !r.quiet {
+			if err := checkBlock(t.Block); err != nil {
+				fmt.Println("Aborting delivery, received a malformed block:", err)
+				if r.monitor {
+					r.alert(fmt.Sprintf("channel %s delivered a malformed block: %s", r.channelID, err))
+				}
+				return cb.Status_UNKNOWN
+			}
+			r.received++
+			r.last = t.Block.Header.Number
+			if r.tally != nil {
+				r.tally.record(t.Block)
+			}
+			if t.Block.Header.Number == 0 && !r.checkGenesis(t.Block) {
+				fmt.Println("Aborting delivery from a ledger with an unexpected genesis block")
+				if r.monitor {
+					r.alert(fmt.Sprintf("the genesis block of channel %s does not match the expected hash", r.channelID))
+				}
+				return cb.Status_UNKNOWN
+			}
+			if r.lastCfg != nil {
+				r.lastCfg.check(t.Block)
+			}
+			if r.configOnly {
+				if !utils.IsConfigBlock(t.Block) {
+					continue
+				}
+				r.configBlocks = append(r.configBlocks, t.Block.Header.Number)
+				if r.configDir != "" {
+					if err := writeRawBlock(r.configDir, t.Block); err != nil {
+						fmt.Printf("Error saving block %d: %s\n", t.Block.Header.Number, err)
+					}
+				}
+			}
+			if r.fields != nil {
+				if err := r.fields.printBlock(r.records, t.Block); err != nil {
+					fmt.Printf("Error selecting the fields of block %d: %s\n", t.Block.Header.Number, err)
+				}
+			} else if r.ndjson {
+				if err := printBlockLine(r.records, t.Block); err != nil {
+					fmt.Printf("Error serializing block %d: %s\n", t.Block.Header.Number, err)
+				}
+			} else if !r.quiet {
 				fmt.Println("Received block: ")
 				err := protolator.DeepMarshalJSON(os.Stdout, t.Block)
 				if err != nil {
@@ -90,69 +375,596 @@ func (r *deliverClient) readUntilClose() {
 			} else {
 				fmt.Println("Received block: ", t.Block.Header.Number)
 			}
+			if r.sizes != nil {
+				r.sizes.record(t.Block)
+			}
+			if r.proveTx != "" && proveInclusion(t.Block, r.proveTx, r.hash) {
+				r.proved = true
+			}
+			checked, verified := false, true
+			if r.dump {
+				dumpSignatures(t.Block)
+			} else if r.verifier != nil && r.verifier.selects(t.Block) {
+				checked, verified = true, r.verifier.verifyAndUpdate(t.Block)
+				if r.verifier.unavailable {
+					fmt.Println("Aborting delivery, no MSP is available to verify signatures")
+					return cb.Status_UNKNOWN
+				}
+			}
+			if r.monitor && !verified {
+				r.quorumAlert(t.Block)
+				return cb.Status_UNKNOWN
+			}
+			if r.manifest != nil {
+				r.manifest.record(t.Block, checked, verified)
+			}
+			if r.forwarder != nil && checked && verified {
+				r.forwarder.forward(t.Block)
+			}
+			if r.checkpoint != "" && r.checkpointValid && checked {
+				r.checkpointValid = verified
+				if verified {
+					if err := writeCheckpoint(r.checkpoint, t.Block.Header.Number); err != nil {
+						fmt.Printf("Error saving checkpoint %s: %s\n", r.checkpoint, err)
+					}
+				}
+			}
 		}
 	}
 }
 
+// readWithRetries reads until the orderer closes the request, resuming
+// delivery up to retries times while the orderer is temporarily unavailable.
+func (r *deliverClient) readWithRetries(retries int) cb.Status {
+	status := r.readUntilClose()
+	for attempt := 1; status == cb.Status_SERVICE_UNAVAILABLE && attempt <= retries; attempt++ {
+		fmt.Printf("Resuming delivery in %s (attempt %d of %d)\n", time.Duration(attempt)*time.Second, attempt, retries)
+		time.Sleep(time.Duration(attempt) * time.Second)
+		if err := r.resume(); err != nil {
+			fmt.Println("Received error:", err)
+			break
+		}
+		status = r.readUntilClose()
+	}
+	return status
+}
+
+// countUntilClose counts the delivered blocks and their transactions until
+// the orderer closes the request, printing the running totals every second.
+func (r *deliverClient) countUntilClose() cb.Status {
+	start := time.Now()
+	lastPrint := start
+	defer func() {
+		elapsed := time.Since(start)
+		fmt.Printf("Received %d blocks and %d transactions in %s (%.1f blocks/s, %.1f tx/s)\n",
+			r.received, r.txs, elapsed, float64(r.received)/elapsed.Seconds(), float64(r.txs)/elapsed.Seconds())
+	}()
+
+	for {
+		msg, err := r.client.Recv()
+		if err != nil {
+			fmt.Println("Error receiving:", err)
+			return cb.Status_UNKNOWN
+		}
+
+		switch t := msg.Type.(type) {
+		case *ab.DeliverResponse_Status:
+			fmt.Println(r.describeStatus(t.Status))
+			return t.Status
+		case *ab.DeliverResponse_Block:
+			if err := checkBlock(t.Block); err != nil {
+				fmt.Println("Aborting delivery, received a malformed block:", err)
+				return cb.Status_UNKNOWN
+			}
+			r.received++
+			r.last = t.Block.Header.Number
+			r.txs += uint64(len(t.Block.Data.Data))
+			if r.tally != nil {
+				r.tally.record(t.Block)
+			}
+			if time.Since(lastPrint) >= time.Second {
+				lastPrint = time.Now()
+				fmt.Printf("Blocks: %d, transactions: %d\n", r.received, r.txs)
+			}
+		}
+	}
+}
+
+// writeRawBlock saves the marshaled block to the file <number>.block of dir.
+func writeRawBlock(dir string, block *cb.Block) error {
+	blockBytes, err := utils.Marshal(block)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.block", block.Header.Number)), blockBytes, 0644)
+}
+
+// printBlockLine writes the block as a single line of compact JSON, wrapped
+// in an object exposing the block number as a top-level field.
+func printBlockLine(w io.Writer, block *cb.Block) error {
+	var pretty, compact bytes.Buffer
+	if err := protolator.DeepMarshalJSON(&pretty, block); err != nil {
+		return err
+	}
+	if err := json.Compact(&compact, pretty.Bytes()); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "{\"number\":%d,\"block\":%s}\n", block.Header.Number, compact.Bytes())
+	return err
+}
+
+// redirectDiagnostics sends everything printed to stdout to stderr instead,
+// returning the original stdout for the JSON records. Every line left on
+// stdout is thus a JSON record.
+func redirectDiagnostics() io.Writer {
+	records := os.Stdout
+	os.Stdout = os.Stderr
+	return records
+}
+
+func positionString(position *ab.SeekPosition) string {
+	switch t := position.Type.(type) {
+	case *ab.SeekPosition_Oldest:
+		return "oldest"
+	case *ab.SeekPosition_Newest:
+		return "newest"
+	case *ab.SeekPosition_Specified:
+		if t.Specified.Number == math.MaxUint64 {
+			return "end"
+		}
+		return fmt.Sprintf("%d", t.Specified.Number)
+	}
+	return "unknown"
+}
+
+// describeStatus explains the status closing a seek request, along with the
+// channel and the requested range.
+func (r *deliverClient) describeStatus(status cb.Status) string {
+	request := fmt.Sprintf("channel %s, blocks %s to %s", r.channelID, positionString(r.start), positionString(r.stop))
+	switch status {
+	case cb.Status_SUCCESS:
+		return fmt.Sprintf("All requested blocks were delivered (%s)", request)
+	case cb.Status_NOT_FOUND:
+		return fmt.Sprintf("The requested blocks are not available yet, or the channel does not exist (%s)", request)
+	case cb.Status_FORBIDDEN:
+		return fmt.Sprintf("The orderer denied access to the channel, check the client identity (%s)", request)
+	case cb.Status_BAD_REQUEST:
+		return fmt.Sprintf("The orderer rejected the seek request as malformed (%s)", request)
+	case cb.Status_SERVICE_UNAVAILABLE:
+		return fmt.Sprintf("The orderer is temporarily unable to serve the channel (%s)", request)
+	default:
+		return fmt.Sprintf("Got unexpected status %s (%s)", status, request)
+	}
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run delivers the requested blocks and returns the exit code of the client.
+func run() int {
 	config, err := config.Load()
 	if err != nil {
 		fmt.Println("failed to load config:", err)
-		os.Exit(1)
+		return exitFailure
 	}
 
 	// Load local MSP
 	err = mspmgmt.LoadLocalMsp(config.General.LocalMSPDir, config.General.BCCSP, config.General.LocalMSPID)
 	if err != nil { // Handle errors reading the config file
 		fmt.Println("Failed to initialize local MSP:", err)
-		os.Exit(0)
+		return exitConfigError
 	}
 
 	signer := localmsp.NewSigner()
 
 	var channelID string
 	var serverAddr string
-	var seek int
+	var positions positionFlags
 	var quiet bool
+	var ndjson bool
+	var countOnly bool
+	var tlsEnabled bool
+	var caFile string
+	var serverName string
+	var n int
+	var f int
+	var verifySample uint64
+	var verifyFrom uint64
+	var verifyTo uint64
+	var strict bool
+	var behavior string
+	var servers string
+	var retries int
+	var checkpoint string
+	var reverse bool
+	var manifestPath string
+	var keepaliveTime time.Duration
+	var hashName string
+	var genesisHash string
+	var configOnly bool
+	var checkLastConfig bool
+	var configDir string
+	var keepaliveTimeout time.Duration
+	var floor uint64
+	var dump bool
+	var sizes bool
+	var proveTx string
+	var forwardAddr string
+	var monitor bool
+	var auditInterval time.Duration
+	var maxPending int
+	var fieldList string
+	var proxyURL string
+	var expectTip uint64
+	var expectTipHash string
+	var timeout time.Duration
+	var expectTxs uint64
+	var expectTolerance uint64
+
+	var defaultCAFile string
+	if len(config.General.TLS.RootCAs) > 0 {
+		defaultCAFile = config.General.TLS.RootCAs[0]
+	}
 
 	flag.StringVar(&serverAddr, "server", fmt.Sprintf("%s:%d", config.General.ListenAddress, config.General.ListenPort), "The RPC server to connect to.")
+	flag.IntVar(&maxPending, "max-pending", 0, "The most blocks awaiting comparison held in memory with -servers, the others are spilled to a temporary file. 0 means no limit.")
+	flag.StringVar(&servers, "servers", "", "A comma-separated list of RPC servers to deliver the same blocks from and compare, overrides -server. Exits with a non-zero code if any block differs, is missing or fails verification.")
 	flag.StringVar(&channelID, "channelID", genesisconfig.TestChainID, "The channel ID to deliver from.")
 	flag.BoolVar(&quiet, "quiet", false, "Only print the block number, will not attempt to print its block contents.")
-	flag.IntVar(&seek, "seek", -2, "Specify the range of requested blocks."+
+	flag.BoolVar(&ndjson, "ndjson", false, "Print every block as a single line of JSON, with the block number as the top-level \"number\" field. Every other message goes to stderr.")
+	flag.StringVar(&fieldList, "fields", "", "Print only these comma-separated paths of the JSON form of every block, such as header.number or data.data[].payload.header.channel_header.tx_id, overrides -ndjson and -quiet. Every other message goes to stderr.")
+	flag.BoolVar(&countOnly, "count-only", false, "Only count the received blocks and transactions, without printing or verifying them.")
+	flag.BoolVar(&configOnly, "config-only", false, "Skip every block but config blocks, reporting the numbers of the config blocks found. Ends at the newest block unless a range is given.")
+	flag.StringVar(&configDir, "config-dir", "", "Save the marshaled config blocks found in -config-only mode to this directory.")
+	flag.IntVar(&positions.seek, "seek", -2, "Specify the range of requested blocks, a legacy shortcut for -start, -stop and -follow."+
 		"Acceptable values:"+
 		"-2 (or -1) to start from oldest (or newest) and keep at it indefinitely."+
 		"N >= 0 to fetch block N only.")
+	flag.StringVar(&positions.start, "start", "", "The first block requested: oldest, newest or a block number. Defaults to oldest.")
+	flag.StringVar(&positions.stop, "stop", "", "The last block requested: oldest, newest or a block number. Defaults to the newest block at the time of the request.")
+	flag.BoolVar(&positions.follow, "follow", false, "Keep waiting for new blocks after -start, instead of stopping.")
+	flag.Uint64Var(&positions.tail, "tail", 0, "Fetch the last N blocks only, overrides -seek.")
+	flag.StringVar(&checkpoint, "checkpoint", "", "A file saving the last block whose signatures were verified, requires -verify-sample. Delivery resumes after it on restart, up to the stop of the range flags.")
+	flag.BoolVar(&reverse, "reverse", false, "Verify the blocks from the newest one down to -floor, fetching them one at a time.")
+	flag.Uint64Var(&floor, "floor", 0, "The lowest block number verified in -reverse mode.")
+	flag.StringVar(&manifestPath, "manifest", "", "Write a JSON summary of the delivered range and of its verification to this file.")
+	flag.IntVar(&retries, "retries", 3, "The number of times to resume delivery when the orderer is temporarily unavailable.")
+	flag.StringVar(&behavior, "behavior", ab.SeekInfo_BLOCK_UNTIL_READY.String(), "The behavior when a requested block is not available yet."+
+		"Acceptable values:"+
+		"BLOCK_UNTIL_READY to wait until the block is produced."+
+		"FAIL_IF_NOT_READY to return a status immediately.")
+	flag.IntVar(&n, "n", 4, "The number of orderers signing the blocks, derived from the channel config if not set.")
+	flag.IntVar(&f, "f", 1, "The number of faulty orderers tolerated, f+1 valid signatures are required per block. Derived as (n-1)/3 if not set.")
+	flag.Uint64Var(&verifySample, "verify-sample", 0, "Verify the signatures of 1 of every K blocks, 0 disables verification.")
+	flag.Uint64Var(&verifyFrom, "verify-from", 0, "The first block number eligible for signature verification.")
+	flag.Uint64Var(&verifyTo, "verify-to", math.MaxUint64, "The last block number eligible for signature verification.")
+	flag.BoolVar(&strict, "strict", false, "Fail the verification of a block on any invalid signature, instead of only on a missing quorum of valid ones.")
+	flag.BoolVar(&dump, "no-verify-count", false, "Dump the signed payload, signature and signer of every block signature instead of verifying them.")
+	flag.BoolVar(&sizes, "metadata-sizes", false, "Print the byte length of every metadata index and the number of signatures of each block.")
+	flag.StringVar(&proveTx, "prove-tx", "", "Print the position of the transaction with this ID and the data hashes proving its inclusion in its block.")
+	flag.Uint64Var(&expectTip, "expect-tip", 0, "Only check that the newest block has this number, waiting up to -timeout for it, and exit with a non-zero code otherwise.")
+	flag.StringVar(&expectTipHash, "expect-tip-hash", "", "Only check that the hex-encoded header hash of the newest block matches this one, combined with -expect-tip if set.")
+	flag.DurationVar(&timeout, "timeout", 0, "The longest time to wait for the newest block to reach -expect-tip.")
+	flag.DurationVar(&auditInterval, "audit-interval", 0, "Verify the whole channel from the genesis block on this interval, logging the outcome of every run as a line of JSON.")
+	flag.BoolVar(&monitor, "monitor", false, "Follow the channel from the newest block unless a range is given, verifying every block, reconnecting when delivery is lost, and exit with an alert on the first block failing to reach quorum.")
+	flag.StringVar(&forwardAddr, "forward", "", "Relay every block whose signatures were verified, length-prefixed, to this address: unix:PATH, tcp:HOST:PORT or HOST:PORT. Requires -verify-sample, blocks skipped by the sample are not relayed.")
+	flag.DurationVar(&keepaliveTime, "keepalive", 0, "Ping the RPC server after this much inactivity to keep long sessions alive, 0 disables keepalive.")
+	flag.DurationVar(&keepaliveTimeout, "keepalive-timeout", 20*time.Second, "Close the connection if a keepalive ping is not answered within this duration.")
+	flag.StringVar(&hashName, "hash", "", "The hashing algorithm of the header and data hash checks, SHA256 or SHA3_256. Defaults to SHA256, which the orderer uses for every channel.")
+	flag.StringVar(&genesisHash, "genesis-hash", "", "The expected hex-encoded header hash of the genesis block, delivery fails if block 0 does not match it.")
+	flag.Uint64Var(&expectTxs, "expect-txs", 0, "Exit with a non-zero code unless the delivered blocks hold this many transactions in total.")
+	flag.Uint64Var(&expectTolerance, "expect-txs-tolerance", 0, "The difference from -expect-txs still accepted.")
+	flag.BoolVar(&checkLastConfig, "check-last-config", false, "Check that the LAST_CONFIG index never goes backward and points at a config block.")
+	flag.BoolVar(&tlsEnabled, "tls", config.General.TLS.Enabled, "Use TLS when connecting to the RPC server.")
+	flag.StringVar(&caFile, "cafile", defaultCAFile, "The PEM-encoded root certificate used to verify the RPC server when TLS is enabled.")
+	flag.StringVar(&proxyURL, "proxy", "", "Tunnel the connections to the RPC server through this proxy: socks5://[USER:PASSWORD@]HOST:PORT or http://[USER:PASSWORD@]HOST:PORT for HTTP CONNECT. "+
+		"TLS, if enabled with -tls, is still negotiated end-to-end with the RPC server. Without it, the HTTPS_PROXY and NO_PROXY environment variables select an HTTP CONNECT proxy.")
+	flag.StringVar(&serverName, "servername", "", "Override the server name expected in the TLS certificate of the RPC server.")
 	flag.Parse()
 
-	if seek < -2 {
-		fmt.Println("Wrong seek value.")
+	var nSet, fSet, expectSet, tipSet bool
+	set := make(map[string]bool)
+	flag.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+		switch fl.Name {
+		case "n":
+			nSet = true
+		case "f":
+			fSet = true
+		case "expect-txs":
+			expectSet = true
+		case "seek":
+			positions.seekSet = true
+		case "expect-tip":
+			tipSet = true
+		}
+	})
+
+	if servers != "" {
+		for _, name := range singleServerFlags {
+			if set[name] {
+				fmt.Printf("-%s applies to delivery from a single orderer and cannot be combined with -servers.\n", name)
+				flag.PrintDefaults()
+				return exitFailure
+			}
+		}
+	}
+
+	seekBehavior, ok := ab.SeekInfo_SeekBehavior_value[behavior]
+	if !ok {
+		fmt.Println("Wrong behavior value:", behavior)
 		flag.PrintDefaults()
+		return exitFailure
 	}
 
-	conn, err := grpc.Dial(serverAddr, grpc.WithInsecure())
+	start, stop, err := positions.resolve(ab.SeekInfo_SeekBehavior(seekBehavior))
 	if err != nil {
-		fmt.Println("Error connecting:", err)
-		return
+		fmt.Println("Wrong range:", err)
+		flag.PrintDefaults()
+		return exitFailure
+	}
+	if configOnly && !positions.explicit() {
+		// A config export ends at the newest block unless asked to follow.
+		stop = newest
 	}
-	client, err := ab.NewAtomicBroadcastClient(conn).Deliver(context.TODO())
+
+	hashFunc, ok := hashAlgorithms[hashName]
+	if hashName != "" && !ok {
+		fmt.Println("Wrong hash value:", hashName)
+		flag.PrintDefaults()
+		return exitFailure
+	}
+
+	expectedGenesis, err := hex.DecodeString(genesisHash)
 	if err != nil {
-		fmt.Println("Error connecting:", err)
-		return
+		fmt.Println("Wrong genesis-hash value:", err)
+		return exitFailure
 	}
 
-	s := newDeliverClient(client, channelID, signer, quiet)
-	switch seek {
-	case -2:
-		err = s.seekOldest()
-	case -1:
-		err = s.seekNewest()
-	default:
-		err = s.seekSingle(uint64(seek))
+	var fields *fieldSelector
+	if fieldList != "" {
+		if fields, err = newFieldSelector(fieldList); err != nil {
+			fmt.Println(err)
+			return exitFailure
+		}
 	}
 
+	expectedTipHash, err := hex.DecodeString(expectTipHash)
 	if err != nil {
+		fmt.Println("Wrong expect-tip-hash value:", err)
+		return exitFailure
+	}
+	if expectTipHash == "" {
+		expectedTipHash = nil
+	}
+
+	records := io.Writer(os.Stdout)
+	if ndjson || fields != nil {
+		records = redirectDiagnostics()
+	}
+
+	opts, err := submit.DialOptions(tlsEnabled, caFile, serverName)
+	if err != nil {
+		fmt.Println(err)
+		return exitFailure
+	}
+	if proxyURL != "" {
+		opt, err := proxyDialOption(proxyURL)
+		if err != nil {
+			fmt.Println(err)
+			return exitFailure
+		}
+		opts = append(opts, opt)
+	}
+	if keepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                keepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	if (monitor || auditInterval > 0) && verifySample == 0 {
+		verifySample = 1
+	}
+	if checkpoint != "" && verifySample == 0 {
+		fmt.Println("-checkpoint saves the last verified block and requires -verify-sample.")
+		flag.PrintDefaults()
+		return exitFailure
+	}
+	if forwardAddr != "" && verifySample == 0 {
+		fmt.Println("-forward relays only verified blocks and requires -verify-sample.")
+		flag.PrintDefaults()
+		return exitFailure
+	}
+	var v *verifier
+	if verifySample > 0 {
+		v = &verifier{channelID: channelID, n: n, f: f, sample: verifySample, from: verifyFrom, to: verifyTo, strict: strict}
+	}
+	// exit reports a config error instead of the given exit code if
+	// signatures could not be verified for lack of a usable MSP.
+	exit := func(code int) int {
+		if v != nil && v.unavailable {
+			return exitConfigError
+		}
+		return code
+	}
+
+	dial := func(addr string) (*grpc.ClientConn, ab.AtomicBroadcast_DeliverClient, error) {
+		conn, err := grpc.Dial(addr, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := ab.NewAtomicBroadcastClient(conn).Deliver(context.TODO())
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		return conn, client, nil
+	}
+
+	connect := func(addr string) (*deliverClient, error) {
+		conn, client, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		s := newDeliverClient(client, channelID, signer, quiet)
+		s.conn = conn
+		s.ndjson = ndjson
+		s.fields = fields
+		s.records = records
+		s.countOnly = countOnly
+		s.configOnly = configOnly
+		s.configDir = configDir
+		s.checkpoint = checkpoint
+		s.checkpointValid = true
+		if manifestPath != "" {
+			s.manifest = newManifestBuilder(channelID, s.hash)
+			s.manifestPath = manifestPath
+		}
+		s.proveTx = proveTx
+		s.behavior = ab.SeekInfo_SeekBehavior(seekBehavior)
+		s.verifier = v
+		s.hashFunc = hashFunc
+		s.genesis = expectedGenesis
+		if checkLastConfig {
+			s.lastCfg = newLastConfigChecker()
+		}
+		s.dump = dump
+		if sizes {
+			s.sizes = newMetadataSizes()
+		}
+		if v != nil && v.bundle == nil {
+			configBlock, err := s.fetchLastConfig()
+			if err != nil {
+				fmt.Printf("Error fetching the last config block of channel %s: %s\n", channelID, err)
+			} else {
+				v.updateConfig(configBlock)
+			}
+			v.deriveQuorum(nSet, fSet)
+		}
+		return s, nil
+	}
+
+	if servers != "" {
+		addrs := strings.Split(servers, ",")
+		clients := make([]*deliverClient, len(addrs))
+		for i, addr := range addrs {
+			clients[i], err = connect(addr)
+			if err != nil {
+				fmt.Printf("Error connecting to %s: %s\n", addr, err)
+				return exitFailure
+			}
+			defer clients[i].close()
+			if positions.tail > 0 {
+				err = clients[i].seekTail(positions.tail)
+			} else {
+				err = clients[i].request(start, stop)
+			}
+			if err != nil {
+				fmt.Printf("Received error from %s: %s\n", addr, err)
+			}
+		}
+		if !compareServers(addrs, clients, v, maxPending) {
+			return exit(exitFailure)
+		}
+		return exit(exitSuccess)
+	}
+
+	s, err := connect(serverAddr)
+	if err != nil {
+		fmt.Println("Error connecting:", err)
+		return exitFailure
+	}
+	defer s.close()
+	if forwardAddr != "" {
+		s.forwarder = newForwarder(forwardAddr)
+		defer s.forwarder.close()
+	}
+	if expectSet {
+		s.tally = &txTally{expected: expectTxs, tolerance: expectTolerance}
+	}
+	s.monitor = monitor
+	if monitor && !positions.explicit() {
+		start, stop = newest, maxStop
+	}
+
+	if tipSet || expectedTipHash != nil {
+		var number *uint64
+		if tipSet {
+			number = &expectTip
+		}
+		if !s.checkTip(number, expectedTipHash, timeout) {
+			return exitFailure
+		}
+		return exitSuccess
+	}
+
+	if auditInterval > 0 {
+		s.audit(auditInterval, retries, func() (*grpc.ClientConn, ab.AtomicBroadcast_DeliverClient, error) {
+			return dial(serverAddr)
+		})
+		return exit(exitFailure)
+	}
+
+	if reverse {
+		if !s.verifyReverse(floor) {
+			return exit(exitFailure)
+		}
+		return exitSuccess
+	}
+
+	if positions.tail > 0 {
+		start, stop, err = s.tailPositions(positions.tail)
+		if err != nil {
+			fmt.Println("Error probing the newest block:", err)
+			return exitFailure
+		}
+	}
+	if checkpoint != "" {
+		number, err := readCheckpoint(checkpoint)
+		if err != nil {
+			fmt.Printf("Not resuming from checkpoint: %s\n", err)
+		} else {
+			resumed, ok, err := s.checkpointStart(number, stop)
+			switch {
+			case err != nil:
+				fmt.Println("Error probing the newest block:", err)
+				return exitFailure
+			case !ok:
+				fmt.Printf("Block %d saved in checkpoint %s already ends the requested range\n", number, checkpoint)
+				return exitSuccess
+			}
+			fmt.Printf("Resuming after block %d saved in checkpoint %s\n", number, checkpoint)
+			start = resumed
+		}
+	}
+
+	if err := s.request(start, stop); err != nil {
 		fmt.Println("Received error:", err)
 	}
 
-	s.readUntilClose()
+	if monitor {
+		return exit(s.monitorUntilAlert(func() (*grpc.ClientConn, ab.AtomicBroadcast_DeliverClient, error) {
+			return dial(serverAddr)
+		}))
+	}
+
+	status := s.readWithRetries(retries)
+	if s.tally != nil && !s.tally.check() {
+		return exit(exitFailure)
+	}
+	return exit(exitCode(status))
+}
+
+// exitCode maps the status closing delivery to the exit code of the client.
+func exitCode(status cb.Status) int {
+	switch status {
+	case cb.Status_SUCCESS:
+		return exitSuccess
+	case cb.Status_NOT_FOUND:
+		return exitNotFound
+	default:
+		return exitFailure
+	}
 }
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// Load the development MSP first, as the BCCSP factories it sets up
+	// are only ever initialized once.
+	if err := mspmgmt.LoadDevMsp(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRunLocalMSPFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deliver_stdout")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("ORDERER_GENERAL_LOCALMSPDIR", dir)
+	defer os.Unsetenv("ORDERER_GENERAL_LOCALMSPDIR")
+
+	assert.Equal(t, exitConfigError, run())
+}
+
+func TestNDJSONKeepsDiagnosticsOffStdout(t *testing.T) {
+	stdout, err := ioutil.TempFile("", "deliver_stdout")
+	assert.NoError(t, err)
+	defer os.Remove(stdout.Name())
+	defer stdout.Close()
+	stderr, err := os.Open(os.DevNull)
+	assert.NoError(t, err)
+	defer stderr.Close()
+	defer func(stdout, stderr *os.File) { os.Stdout, os.Stderr = stdout, stderr }(os.Stdout, os.Stderr)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	genesis := &cb.Block{Header: &cb.BlockHeader{Number: 0}, Data: &cb.BlockData{Data: [][]byte{utils.MarshalOrPanic(&cb.Envelope{})}}}
+	for _, fields := range []string{"", "header.number"} {
+		client := &mockDeliverClient{responses: []*ab.DeliverResponse{
+			{Type: &ab.DeliverResponse_Block{Block: genesis}},
+			{Type: &ab.DeliverResponse_Status{Status: cb.Status_SUCCESS}},
+		}}
+		r := newDeliverClient(client, "foo", nil, false)
+		r.start, r.stop = oldest, newest
+		r.ndjson = true
+		if fields != "" {
+			r.fields, err = newFieldSelector(fields)
+			assert.NoError(t, err)
+		}
+		r.verifier = &verifier{channelID: "foo", n: 1, f: 0, sample: 1, to: 10}
+		r.records = redirectDiagnostics()
+		assert.Equal(t, cb.Status_SUCCESS, r.readUntilClose())
+		os.Stdout = stdout
+	}
+
+	output, err := ioutil.ReadFile(stdout.Name())
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), "not a JSON line: %s", line)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+type deliveredBlock struct {
+	server int
+	block  *cb.Block
+	bytes  []byte
+
+	// spilled blocks keep only the location of their bytes in the spill
+	// file.
+	spilled bool
+	offset  int64
+	size    int
+}
+
+// pendingBlocks buffers the blocks delivered by some orderers only. Once
+// max blocks are held in memory, the bytes of the next ones are spilled to
+// a temporary file and read back when compared; max 0 never spills.
+type pendingBlocks struct {
+	max      int
+	inMemory int
+	byNumber map[uint64][]*deliveredBlock
+
+	spill     *os.File
+	spillSize int64
+}
+
+func newPendingBlocks(max int) *pendingBlocks {
+	return &pendingBlocks{max: max, byNumber: make(map[uint64][]*deliveredBlock)}
+}
+
+// add buffers the block and returns all the versions of its number
+// delivered so far.
+func (p *pendingBlocks) add(b *deliveredBlock) []*deliveredBlock {
+	if p.max > 0 && p.inMemory >= p.max {
+		if err := p.spillBlock(b); err != nil {
+			fmt.Printf("Error spilling block %d, keeping it in memory: %s\n", b.block.Header.Number, err)
+		}
+	}
+	if !b.spilled {
+		p.inMemory++
+	}
+	number := b.block.Header.Number
+	p.byNumber[number] = append(p.byNumber[number], b)
+	return p.byNumber[number]
+}
+
+func (p *pendingBlocks) spillBlock(b *deliveredBlock) error {
+	if p.spill == nil {
+		f, err := ioutil.TempFile("", "deliver_stdout")
+		if err != nil {
+			return err
+		}
+		p.spill = f
+		fmt.Printf("More than %d blocks pending comparison, spilling to %s\n", p.max, f.Name())
+	}
+	if _, err := p.spill.WriteAt(b.bytes, p.spillSize); err != nil {
+		return err
+	}
+	b.offset, b.size = p.spillSize, len(b.bytes)
+	p.spillSize += int64(b.size)
+	b.spilled, b.bytes = true, nil
+	b.block = &cb.Block{Header: b.block.Header}
+	return nil
+}
+
+// take removes the versions of the block with the given number from the
+// buffer, reading the spilled ones back.
+func (p *pendingBlocks) take(number uint64) ([]*deliveredBlock, error) {
+	delivered := p.byNumber[number]
+	delete(p.byNumber, number)
+	for _, b := range delivered {
+		if !b.spilled {
+			p.inMemory--
+			continue
+		}
+		b.bytes = make([]byte, b.size)
+		if _, err := p.spill.ReadAt(b.bytes, b.offset); err != nil {
+			return nil, err
+		}
+		block, err := utils.GetBlockFromBlockBytes(b.bytes)
+		if err != nil {
+			return nil, err
+		}
+		b.block, b.spilled = block, false
+	}
+	return delivered, nil
+}
+
+// close removes the spill file, if any.
+func (p *pendingBlocks) close() {
+	if p.spill != nil {
+		p.spill.Close()
+		os.Remove(p.spill.Name())
+	}
+}
+
+// compareServers reads the blocks delivered by every client, one per orderer,
+// and reports for each block number whether all orderers returned
+// byte-identical blocks. The signatures of every distinct version of a block
+// are verified once, if the verifier selects it, against the channel config
+// in force before the block. The config a block carries is only loaded if
+// all the orderers returned it identically and it passed. At most maxPending
+// blocks awaiting comparison are held in memory, 0 meaning no limit. It
+// returns whether every orderer delivered the whole range and every block
+// was identical across them and passed verification.
+func compareServers(servers []string, clients []*deliverClient, v *verifier, maxPending int) bool {
+	blocks := make(chan deliveredBlock)
+	// failed records the orderers whose delivery ended in error, each
+	// written by its own goroutine only.
+	failed := make([]bool, len(clients))
+	var wg sync.WaitGroup
+	wg.Add(len(clients))
+	for i, c := range clients {
+		go func(i int, c *deliverClient) {
+			defer wg.Done()
+			for {
+				msg, err := c.client.Recv()
+				if err != nil {
+					fmt.Printf("%s: error receiving: %s\n", servers[i], err)
+					failed[i] = true
+					return
+				}
+				switch t := msg.Type.(type) {
+				case *ab.DeliverResponse_Status:
+					fmt.Printf("%s: got status %s\n", servers[i], t.Status)
+					failed[i] = t.Status != cb.Status_SUCCESS
+					return
+				case *ab.DeliverResponse_Block:
+					if err := checkBlock(t.Block); err != nil {
+						fmt.Printf("%s: received a malformed block: %s\n", servers[i], err)
+						failed[i] = true
+						return
+					}
+					blocks <- deliveredBlock{server: i, block: t.Block, bytes: utils.MarshalOrPanic(t.Block)}
+				}
+			}
+		}(i, c)
+	}
+	go func() {
+		wg.Wait()
+		close(blocks)
+	}()
+
+	ok := true
+	pending := newPendingBlocks(maxPending)
+	defer pending.close()
+	for b := range blocks {
+		b := b
+		if len(pending.add(&b)) == len(servers) {
+			ok = compareTaken(servers, pending, b.block.Header.Number, v) && ok
+		}
+	}
+
+	var numbers []uint64
+	for number := range pending.byNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	for _, number := range numbers {
+		ok = compareTaken(servers, pending, number, v) && ok
+	}
+	for _, f := range failed {
+		ok = ok && !f
+	}
+	return ok
+}
+
+// compareTaken compares the versions of a block once taken out of pending.
+func compareTaken(servers []string, pending *pendingBlocks, number uint64, v *verifier) bool {
+	delivered, err := pending.take(number)
+	if err != nil {
+		fmt.Printf("Block %d: error reading back spilled versions: %s\n", number, err)
+		return false
+	}
+	return compareBlock(servers, number, delivered, v)
+}
+
+// compareBlock groups the versions of a block by content and reports any
+// mismatch or any orderer which did not deliver the block. It returns whether
+// all the orderers delivered the same block and it passed verification, if
+// selected.
+func compareBlock(servers []string, number uint64, delivered []*deliveredBlock, v *verifier) bool {
+	var distinct []*deliveredBlock
+	owners := make(map[string][]string)
+	for _, b := range delivered {
+		key := string(b.bytes)
+		if _, ok := owners[key]; !ok {
+			distinct = append(distinct, b)
+		}
+		owners[key] = append(owners[key], servers[b.server])
+	}
+
+	ok := len(distinct) == 1 && len(delivered) == len(servers)
+	switch {
+	case len(distinct) > 1:
+		fmt.Printf("Block %d: MISMATCH, %d distinct versions\n", number, len(distinct))
+		for _, b := range distinct {
+			fmt.Printf("  %x returned by %s\n", util.ComputeSHA256(b.bytes), strings.Join(owners[string(b.bytes)], ", "))
+		}
+	case len(delivered) < len(servers):
+		fmt.Printf("Block %d: returned by %d of %d orderers only (%s)\n", number, len(delivered), len(servers), strings.Join(owners[string(distinct[0].bytes)], ", "))
+	default:
+		fmt.Printf("Block %d: identical across all %d orderers\n", number, len(servers))
+	}
+
+	if v == nil || !v.selects(distinct[0].block) {
+		return ok
+	}
+	if len(distinct) > 1 {
+		for _, b := range distinct {
+			v.verify(b.block)
+		}
+		if utils.IsConfigBlock(distinct[0].block) {
+			fmt.Printf("Block %d: ignoring the channel config, the orderers disagree on it\n", number)
+		}
+		return false
+	}
+	return v.verifyAndUpdate(distinct[0].block) && ok
+}
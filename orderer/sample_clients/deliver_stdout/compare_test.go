@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func delivered(server int, number uint64, data string) *deliveredBlock {
+	block := &cb.Block{Header: &cb.BlockHeader{Number: number}, Data: &cb.BlockData{Data: [][]byte{[]byte(data)}}}
+	return &deliveredBlock{server: server, block: block, bytes: utils.MarshalOrPanic(block)}
+}
+
+func TestPendingBlocksSpill(t *testing.T) {
+	p := newPendingBlocks(2)
+	assert.Len(t, p.add(delivered(0, 1, "a")), 1)
+	assert.Len(t, p.add(delivered(0, 2, "b")), 1)
+	assert.Len(t, p.add(delivered(0, 3, "c")), 1)
+	assert.Len(t, p.add(delivered(1, 3, "d")), 2)
+	assert.Equal(t, 2, p.inMemory)
+	assert.NotNil(t, p.spill)
+	spill := p.spill.Name()
+
+	versions, err := p.take(3)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	for i, data := range []string{"c", "d"} {
+		expected := delivered(i, 3, data)
+		assert.Equal(t, expected.bytes, versions[i].bytes)
+		assert.True(t, proto.Equal(expected.block, versions[i].block))
+	}
+
+	versions, err = p.take(1)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+	assert.Equal(t, 1, p.inMemory)
+
+	p.close()
+	_, err = os.Stat(spill)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPendingBlocksUnbounded(t *testing.T) {
+	p := newPendingBlocks(0)
+	defer p.close()
+	for number := uint64(0); number < 10; number++ {
+		p.add(delivered(0, number, "a"))
+	}
+	assert.Equal(t, 10, p.inMemory)
+	assert.Nil(t, p.spill)
+}
+
+func TestCompareBlock(t *testing.T) {
+	servers := []string{"a", "b"}
+	assert.True(t, compareBlock(servers, 1, []*deliveredBlock{delivered(0, 1, "x"), delivered(1, 1, "x")}, nil))
+	assert.False(t, compareBlock(servers, 1, []*deliveredBlock{delivered(0, 1, "x"), delivered(1, 1, "y")}, nil))
+	assert.False(t, compareBlock(servers, 1, []*deliveredBlock{delivered(0, 1, "x")}, nil))
+
+	v := &verifier{channelID: "foo", n: 1, f: 0, sample: 1, to: 10}
+	assert.False(t, compareBlock(servers, 1, []*deliveredBlock{delivered(0, 1, "x"), delivered(1, 1, "x")}, v))
+	assert.Equal(t, uint64(1), v.failed)
+}
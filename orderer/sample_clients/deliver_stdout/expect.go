@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// txTally counts the transactions of the delivered blocks and checks their
+// total against the number expected, give or take tolerance. Only counters
+// are kept, so that long ranges are tallied in constant memory.
+type txTally struct {
+	expected  uint64
+	tolerance uint64
+
+	total uint64
+	// blocks counts the delivered blocks, nonEmpty those holding at least
+	// one transaction.
+	blocks   uint64
+	nonEmpty uint64
+	// first and last are the numbers of the first and last delivered block.
+	first uint64
+	last  uint64
+}
+
+func (t *txTally) record(block *cb.Block) {
+	var txs uint64
+	if block.Data != nil {
+		txs = uint64(len(block.Data.Data))
+	}
+	if t.blocks == 0 {
+		t.first = block.Header.Number
+	}
+	t.last = block.Header.Number
+	t.blocks++
+	if txs > 0 {
+		t.nonEmpty++
+	}
+	t.total += txs
+}
+
+// check reports whether the total matches the expected number, printing the
+// discrepancy and the range of blocks involved otherwise.
+func (t *txTally) check() bool {
+	var diff uint64
+	if t.total > t.expected {
+		diff = t.total - t.expected
+	} else {
+		diff = t.expected - t.total
+	}
+	if diff <= t.tolerance {
+		fmt.Printf("Found %d transactions, %d expected (tolerance %d)\n", t.total, t.expected, t.tolerance)
+		return true
+	}
+
+	fmt.Printf("MISMATCH: found %d transactions in %d blocks, %d expected (tolerance %d), off by %d\n", t.total, t.blocks, t.expected, t.tolerance, diff)
+	if t.blocks > 0 {
+		fmt.Printf("  blocks %d to %d, %d of them holding transactions\n", t.first, t.last, t.nonEmpty)
+	}
+	return false
+}
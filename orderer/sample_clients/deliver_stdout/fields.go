@@ -0,0 +1,260 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/tools/protolator"
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// fieldKeyRegexp matches a key of a field path, suffixed with [] to select
+// the field in every element of an array.
+var fieldKeyRegexp = regexp.MustCompile(`^([A-Za-z0-9_]+)(\[\])?$`)
+
+type fieldKey struct {
+	name string
+	each bool
+}
+
+// fieldPath is a dot-separated path into the JSON form of a block, such as
+// data.data[].payload.header.channel_header.tx_id.
+type fieldPath struct {
+	raw  string
+	keys []fieldKey
+}
+
+// fieldSelector prints the selected fields of every block instead of the
+// whole block. It keeps track of the paths matching no block at all, such as
+// misspelled keys below fields which could not be checked at parse time.
+type fieldSelector struct {
+	paths   []fieldPath
+	matched []bool
+}
+
+// newFieldSelector parses a comma-separated list of field paths, checking
+// each one against the fields of a block.
+func newFieldSelector(list string) (*fieldSelector, error) {
+	s := &fieldSelector{}
+	for _, raw := range strings.Split(list, ",") {
+		path, err := parseFieldPath(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFieldPath(&cb.Block{}, path.keys); err != nil {
+			return nil, fmt.Errorf("wrong field path %q: %s", path.raw, err)
+		}
+		s.paths = append(s.paths, path)
+	}
+	s.matched = make([]bool, len(s.paths))
+	return s, nil
+}
+
+func parseFieldPath(raw string) (fieldPath, error) {
+	path := fieldPath{raw: raw}
+	for _, key := range strings.Split(raw, ".") {
+		m := fieldKeyRegexp.FindStringSubmatch(key)
+		if m == nil {
+			return fieldPath{}, fmt.Errorf("wrong field path %q: key %q is not a field name, optionally suffixed with []", raw, key)
+		}
+		path.keys = append(path.keys, fieldKey{name: m[1], each: m[2] != ""})
+	}
+	return path, nil
+}
+
+// checkFieldPath checks the keys against the fields of msg, following the
+// opaque fields which the JSON form of a block expands. The keys below a
+// field whose type depends on the contents of the block, such as the data of
+// a payload, or below a map cannot be checked and are accepted.
+func checkFieldPath(msg proto.Message, keys []fieldKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	key := keys[0]
+	if variableField(msg, key.name) {
+		return nil
+	}
+
+	t := reflect.TypeOf(msg).Elem()
+	props := proto.GetProperties(t)
+	if _, ok := props.OneofTypes[key.name]; ok {
+		return nil
+	}
+	var prop *proto.Properties
+	for _, p := range props.Prop {
+		if p.OrigName == key.name {
+			prop = p
+		}
+	}
+	if prop == nil {
+		return fmt.Errorf("%s has no field %s", proto.MessageName(msg), key.name)
+	}
+	field, _ := t.FieldByName(prop.Name)
+	fieldType := field.Type
+	repeated := fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8
+	if key.each && !repeated {
+		return fmt.Errorf("%s is not an array", key.name)
+	}
+	if len(keys) == 1 {
+		return nil
+	}
+	if repeated {
+		if !key.each {
+			return fmt.Errorf("%s is an array, select its elements with %s[]", key.name, key.name)
+		}
+		fieldType = fieldType.Elem()
+	}
+
+	var next proto.Message
+	var err error
+	if m, ok := msg.(protolator.StaticallyOpaqueMapFieldProto); ok && hasField(m.StaticallyOpaqueMapFields(), key.name) {
+		return nil
+	}
+	if m, ok := msg.(protolator.StaticallyOpaqueFieldProto); ok && hasField(m.StaticallyOpaqueFields(), key.name) {
+		next, err = m.StaticallyOpaqueFieldProto(key.name)
+	}
+	if m, ok := msg.(protolator.StaticallyOpaqueSliceFieldProto); ok && hasField(m.StaticallyOpaqueSliceFields(), key.name) {
+		next, err = m.StaticallyOpaqueSliceFieldProto(key.name, 0)
+	}
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		if fieldType.Kind() == reflect.Map {
+			return nil
+		}
+		if fieldType.Kind() == reflect.Ptr {
+			next, _ = reflect.New(fieldType.Elem()).Interface().(proto.Message)
+		}
+	}
+	if _, ok := next.(interface{ XXX_WellKnownType() string }); next == nil || ok {
+		return fmt.Errorf("cannot select %s, its parent is not an object", keys[1].name)
+	}
+	return checkFieldPath(next, keys[1:])
+}
+
+// variableField reports whether the type of the named field of msg depends on
+// the contents of the block.
+func variableField(msg proto.Message, name string) bool {
+	var names []string
+	if m, ok := msg.(protolator.VariablyOpaqueFieldProto); ok {
+		names = append(names, m.VariablyOpaqueFields()...)
+	}
+	if m, ok := msg.(protolator.VariablyOpaqueSliceFieldProto); ok {
+		names = append(names, m.VariablyOpaqueSliceFields()...)
+	}
+	if m, ok := msg.(protolator.VariablyOpaqueMapFieldProto); ok {
+		names = append(names, m.VariablyOpaqueMapFields()...)
+	}
+	if m, ok := msg.(protolator.DynamicFieldProto); ok {
+		names = append(names, m.DynamicFields()...)
+	}
+	if m, ok := msg.(protolator.DynamicSliceFieldProto); ok {
+		names = append(names, m.DynamicSliceFields()...)
+	}
+	if m, ok := msg.(protolator.DynamicMapFieldProto); ok {
+		names = append(names, m.DynamicMapFields()...)
+	}
+	return hasField(names, name)
+}
+
+func hasField(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectKeys returns the value at the end of the keys, an array of values
+// for every key selecting all the elements of an array, and whether the
+// keys were found.
+func selectKeys(value interface{}, keys []fieldKey) (interface{}, bool, error) {
+	if len(keys) == 0 {
+		return value, true, nil
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("cannot select %s, its parent is not an object", keys[0].name)
+	}
+	child, ok := object[keys[0].name]
+	if !ok {
+		return nil, false, nil
+	}
+	elements, isArray := child.([]interface{})
+	if !keys[0].each {
+		if isArray && len(keys) > 1 {
+			return nil, false, fmt.Errorf("%s is an array, select its elements with %s[]", keys[0].name, keys[0].name)
+		}
+		return selectKeys(child, keys[1:])
+	}
+	if !isArray {
+		return nil, false, fmt.Errorf("%s is not an array", keys[0].name)
+	}
+
+	values := make([]interface{}, 0, len(elements))
+	found := len(elements) == 0
+	for _, element := range elements {
+		v, ok, err := selectKeys(element, keys[1:])
+		if err != nil {
+			return nil, false, err
+		}
+		found = found || ok
+		values = append(values, v)
+	}
+	return values, found, nil
+}
+
+// printBlock writes the selected fields of the block as a single line of
+// JSON, absent fields being null.
+func (s *fieldSelector) printBlock(w io.Writer, block *cb.Block) error {
+	var buf bytes.Buffer
+	if err := protolator.DeepMarshalJSON(&buf, block); err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(buf.Bytes(), &value); err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{}, len(s.paths))
+	for i, path := range s.paths {
+		v, found, err := selectKeys(value, path.keys)
+		if err != nil {
+			return fmt.Errorf("field path %q: %s", path.raw, err)
+		}
+		s.matched[i] = s.matched[i] || found
+		fields[path.raw] = v
+	}
+	line, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "{\"number\":%d,\"fields\":%s}\n", block.Header.Number, line)
+	return err
+}
+
+// report warns about the paths which matched none of the received blocks.
+func (s *fieldSelector) report(received uint64) {
+	if received == 0 {
+		return
+	}
+	for i, path := range s.paths {
+		if !s.matched[i] {
+			fmt.Printf("Field path %q matched none of the %d blocks received\n", path.raw, received)
+		}
+	}
+}
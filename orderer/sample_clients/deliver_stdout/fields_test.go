@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/tools/protolator"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func blockJSON(t *testing.T) interface{} {
+	env := &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+		Header: &cb.Header{ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{Type: int32(cb.HeaderType_MESSAGE), TxId: "tx1"})},
+	})}
+	block := &cb.Block{Header: &cb.BlockHeader{Number: 7}, Data: &cb.BlockData{Data: [][]byte{utils.MarshalOrPanic(env)}}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, protolator.DeepMarshalJSON(&buf, block))
+	var value interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &value))
+	return value
+}
+
+func TestSelectFields(t *testing.T) {
+	value := blockJSON(t)
+	for _, test := range []struct {
+		path     string
+		expected interface{}
+		found    bool
+		err      string
+	}{
+		{path: "header.number", expected: "7", found: true},
+		{path: "data.data[].payload.header.channel_header.tx_id", expected: []interface{}{"tx1"}, found: true},
+		{path: "header.numbr", found: false},
+		{path: "data.data.payload", err: "data is an array, select its elements with data[]"},
+		{path: "header[]", err: "header is not an array"},
+		{path: "header.number.low", err: "cannot select low, its parent is not an object"},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			path, err := parseFieldPath(test.path)
+			assert.NoError(t, err)
+			v, found, err := selectKeys(value, path.keys)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.found, found)
+			assert.Equal(t, test.expected, v)
+		})
+	}
+}
+
+func TestNewFieldSelectorWrongPath(t *testing.T) {
+	_, err := newFieldSelector("header.number,data..data")
+	assert.EqualError(t, err, `wrong field path "data..data": key "" is not a field name, optionally suffixed with []`)
+}
+
+func TestNewFieldSelectorUnknownField(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		err  string
+	}{
+		{path: "header.numbr", err: "common.BlockHeader has no field numbr"},
+		{path: "data.data[].payload.header.channel_header.txid", err: "common.ChannelHeader has no field txid"},
+		{path: "data.data.payload", err: "data is an array, select its elements with data[]"},
+		{path: "header[]", err: "header is not an array"},
+		{path: "header.number.low", err: "cannot select low, its parent is not an object"},
+		{path: "data.data[].payload.header.channel_header.timestamp.seconds", err: "cannot select seconds, its parent is not an object"},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			_, err := newFieldSelector(test.path)
+			assert.EqualError(t, err, fmt.Sprintf("wrong field path %q: %s", test.path, test.err))
+		})
+	}
+
+	s, err := newFieldSelector("header.number, data.data[].payload.header.channel_header.tx_id, data.data[].payload.data.config.sequence, metadata.metadata")
+	assert.NoError(t, err)
+	assert.Len(t, s.paths, 4)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// forwarder relays the verified blocks to a downstream process, each one as
+// a marshaled block preceded by its length encoded as an 8-byte big-endian
+// unsigned integer. The capture files of broadcast_replay use the same
+// framing, but hold envelopes rather than blocks. Writes block while the
+// downstream is slow or gone, so that no verified block is ever dropped.
+type forwarder struct {
+	network string
+	addr    string
+	conn    net.Conn
+	w       *bufio.Writer
+}
+
+// newForwarder parses an address of the form unix:PATH, tcp:HOST:PORT or
+// HOST:PORT. The connection is opened on the first block forwarded.
+func newForwarder(addr string) *forwarder {
+	network := "tcp"
+	if i := strings.Index(addr, ":"); i >= 0 && (addr[:i] == "unix" || addr[:i] == "tcp") {
+		network, addr = addr[:i], addr[i+1:]
+	}
+	return &forwarder{network: network, addr: addr}
+}
+
+// forward writes the block to the downstream, reconnecting and writing it
+// again until it succeeds.
+func (fw *forwarder) forward(block *cb.Block) {
+	data := utils.MarshalOrPanic(block)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Second)
+		}
+		if fw.conn == nil {
+			conn, err := net.Dial(fw.network, fw.addr)
+			if err != nil {
+				fmt.Printf("Error connecting to downstream %s: %s\n", fw.addr, err)
+				continue
+			}
+			fw.conn = conn
+			fw.w = bufio.NewWriter(conn)
+		}
+		err := fw.write(data)
+		if err == nil {
+			return
+		}
+		fmt.Printf("Error forwarding block %d to %s: %s\n", block.Header.Number, fw.addr, err)
+		fw.close()
+	}
+}
+
+func (fw *forwarder) write(data []byte) error {
+	if err := binary.Write(fw.w, binary.BigEndian, uint64(len(data))); err != nil {
+		return err
+	}
+	if _, err := fw.w.Write(data); err != nil {
+		return err
+	}
+	return fw.w.Flush()
+}
+
+func (fw *forwarder) close() {
+	if fw.conn != nil {
+		fw.conn.Close()
+		fw.conn = nil
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// hashAlgorithms maps the accepted values of the -hash flag to their
+// hashing functions.
+var hashAlgorithms = map[string]func([]byte) []byte{
+	bccsp.SHA256:   util.ComputeSHA256,
+	bccsp.SHA3_256: util.ComputeSHA3256,
+}
+
+// hash computes the hash of the data, for header and data hash checks, with
+// the algorithm set by the -hash flag, or else SHA256. The orderer hashes
+// block headers and data with SHA256 whatever the hashing algorithm of the
+// channel config.
+func (r *deliverClient) hash(data []byte) []byte {
+	if r.hashFunc != nil {
+		return r.hashFunc(data)
+	}
+	return util.ComputeSHA256(data)
+}
+
+// checkGenesis prints the header hash of the genesis block and returns
+// whether it matches the expected one, if any.
+func (r *deliverClient) checkGenesis(block *cb.Block) bool {
+	computed := r.hash(block.Header.Bytes())
+	fmt.Printf("Genesis block hash: %x\n", computed)
+	if len(r.genesis) == 0 || bytes.Equal(computed, r.genesis) {
+		return true
+	}
+	fmt.Printf("Genesis block hash does not match the expected %x\n", r.genesis)
+	return false
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// lastConfigChecker checks the consistency of the LAST_CONFIG metadata
+// across the delivered blocks: the index never goes backward and points at
+// a config block, as far as the delivered blocks allow to tell.
+type lastConfigChecker struct {
+	received bool
+	first    uint64
+	index    uint64
+	configs  map[uint64]bool
+
+	inconsistencies uint64
+}
+
+func newLastConfigChecker() *lastConfigChecker {
+	return &lastConfigChecker{configs: make(map[uint64]bool)}
+}
+
+// check validates the LAST_CONFIG index of the block, printing and counting
+// any inconsistency, and returns whether the block passed.
+func (c *lastConfigChecker) check(block *cb.Block) bool {
+	number := block.Header.Number
+	if !c.received {
+		c.first = number
+	}
+	if utils.IsConfigBlock(block) {
+		c.configs[number] = true
+	}
+
+	index, err := lastConfigIndex(block)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("cannot decode LAST_CONFIG metadata: %s", err)
+	case c.received && index < c.index:
+		err = fmt.Errorf("LAST_CONFIG index %d goes backward from %d", index, c.index)
+	case index > number:
+		err = fmt.Errorf("LAST_CONFIG index %d points after the block", index)
+	case index >= c.first && !c.configs[index]:
+		err = fmt.Errorf("LAST_CONFIG index %d does not point at a config block", index)
+	}
+	c.received = true
+
+	if err != nil {
+		c.inconsistencies++
+		fmt.Printf("Block %d: %s\n", number, err)
+		return false
+	}
+	c.index = index
+	return true
+}
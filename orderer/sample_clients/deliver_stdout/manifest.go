@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// Outcomes of a check recorded in a manifest.
+const (
+	checkPass      = "pass"
+	checkFail      = "fail"
+	checkUnchecked = "unchecked"
+)
+
+// manifest summarizes a verified range of blocks. It contains no timestamp
+// or other run-dependent value, so that manifests of the same range can be
+// compared across runs and orderers.
+type manifest struct {
+	Channel      string `json:"channel"`
+	FirstBlock   uint64 `json:"first_block"`
+	FirstHash    string `json:"first_hash"`
+	LastBlock    uint64 `json:"last_block"`
+	LastHash     string `json:"last_hash"`
+	Blocks       uint64 `json:"blocks"`
+	Transactions uint64 `json:"transactions"`
+	Signatures   string `json:"signatures"`
+	DataHash     string `json:"data_hash"`
+	HashChain    string `json:"hash_chain"`
+}
+
+type manifestBuilder struct {
+	manifest
+	prev *cb.Block
+	hash func([]byte) []byte
+}
+
+func newManifestBuilder(channelID string, hash func([]byte) []byte) *manifestBuilder {
+	return &manifestBuilder{hash: hash, manifest: manifest{
+		Channel:    channelID,
+		Signatures: checkUnchecked,
+		DataHash:   checkPass,
+		HashChain:  checkPass,
+	}}
+}
+
+// record accounts for a delivered block, given whether its signatures were
+// checked and, if so, whether they passed.
+func (b *manifestBuilder) record(block *cb.Block, signaturesChecked bool, signaturesOK bool) {
+	hash := hex.EncodeToString(b.hash(block.Header.Bytes()))
+	if b.Blocks == 0 {
+		b.FirstBlock, b.FirstHash = block.Header.Number, hash
+	}
+	b.LastBlock, b.LastHash = block.Header.Number, hash
+	b.Blocks++
+	if block.Data != nil {
+		b.Transactions += uint64(len(block.Data.Data))
+	}
+
+	if signaturesChecked && b.Signatures != checkFail {
+		b.Signatures = checkPass
+		if !signaturesOK {
+			b.Signatures = checkFail
+		}
+	}
+	if verifyDataHash(block, b.hash) != nil {
+		b.DataHash = checkFail
+	}
+	if b.prev != nil && (b.prev.Header.Number+1 != block.Header.Number || verifyHashChain(b.prev, block, b.hash) != nil) {
+		b.HashChain = checkFail
+	}
+	b.prev = block
+}
+
+// write saves the manifest as indented JSON.
+func (b *manifestBuilder) write(path string) error {
+	content, err := json.MarshalIndent(&b.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote manifest of blocks %d to %d to %s\n", b.FirstBlock, b.LastBlock, path)
+	return nil
+}
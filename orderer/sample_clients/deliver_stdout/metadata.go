@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// checkBlock fails if the block lacks the header or data which the handling
+// of every delivered block relies on.
+func checkBlock(block *cb.Block) error {
+	if block == nil {
+		return fmt.Errorf("empty block")
+	}
+	if block.Header == nil {
+		return fmt.Errorf("block has no header")
+	}
+	if block.Data == nil {
+		return fmt.Errorf("block %d has no data", block.Header.Number)
+	}
+	return nil
+}
+
+// blockMetadata unmarshals the metadata at the given index of the block,
+// failing rather than panicking if the block lacks it.
+func blockMetadata(block *cb.Block, index cb.BlockMetadataIndex) (*cb.Metadata, error) {
+	if block.Metadata == nil {
+		return nil, fmt.Errorf("block has no metadata")
+	}
+	if int(index) >= len(block.Metadata.Metadata) {
+		return nil, fmt.Errorf("block has %d metadata indexes, %s missing", len(block.Metadata.Metadata), index)
+	}
+	md := &cb.Metadata{}
+	if err := proto.Unmarshal(block.Metadata.Metadata[index], md); err != nil {
+		return nil, fmt.Errorf("error unmarshaling %s metadata: %s", index, err)
+	}
+	return md, nil
+}
+
+// lastConfigIndex returns the index of the last config block encoded in the
+// LAST_CONFIG metadata of the block.
+func lastConfigIndex(block *cb.Block) (uint64, error) {
+	md, err := blockMetadata(block, cb.BlockMetadataIndex_LAST_CONFIG)
+	if err != nil {
+		return 0, err
+	}
+	lc := &cb.LastConfig{}
+	if err := proto.Unmarshal(md.Value, lc); err != nil {
+		return 0, fmt.Errorf("error unmarshaling LAST_CONFIG value: %s", err)
+	}
+	return lc.Index, nil
+}
+
+// metadataSizes records the byte length of every metadata index of the
+// delivered blocks, along with the number of block signatures, to help
+// estimating the storage and bandwidth overhead of the signatures.
+type metadataSizes struct {
+	blocks uint64
+	min    []int
+	max    []int
+	total  []int
+
+	minSignatures   int
+	maxSignatures   int
+	totalSignatures int
+}
+
+func newMetadataSizes() *metadataSizes {
+	n := len(cb.BlockMetadataIndex_name)
+	return &metadataSizes{min: make([]int, n), max: make([]int, n), total: make([]int, n)}
+}
+
+// record prints the metadata sizes of the block and aggregates them.
+func (m *metadataSizes) record(block *cb.Block) {
+	signatures := 0
+	if meta, err := blockMetadata(block, cb.BlockMetadataIndex_SIGNATURES); err == nil {
+		signatures = len(meta.Signatures)
+	}
+
+	var sizes []string
+	for i := range m.total {
+		size := 0
+		if block.Metadata != nil && i < len(block.Metadata.Metadata) {
+			size = len(block.Metadata.Metadata[i])
+		}
+		sizes = append(sizes, fmt.Sprintf("%s=%d", cb.BlockMetadataIndex(i), size))
+
+		if m.blocks == 0 || size < m.min[i] {
+			m.min[i] = size
+		}
+		if size > m.max[i] {
+			m.max[i] = size
+		}
+		m.total[i] += size
+	}
+
+	if m.blocks == 0 || signatures < m.minSignatures {
+		m.minSignatures = signatures
+	}
+	if signatures > m.maxSignatures {
+		m.maxSignatures = signatures
+	}
+	m.totalSignatures += signatures
+	m.blocks++
+
+	fmt.Printf("Block %d metadata sizes: %s, %d signatures\n", block.Header.Number, strings.Join(sizes, " "), signatures)
+}
+
+// report prints the min/max/mean metadata sizes over all recorded blocks.
+func (m *metadataSizes) report() {
+	if m.blocks == 0 {
+		return
+	}
+	fmt.Printf("Metadata sizes over %d blocks (min/max/mean):\n", m.blocks)
+	for i := range m.total {
+		fmt.Printf("  %s: %d/%d/%.1f bytes\n", cb.BlockMetadataIndex(i), m.min[i], m.max[i], float64(m.total[i])/float64(m.blocks))
+	}
+	fmt.Printf("  signatures: %d/%d/%.1f\n", m.minSignatures, m.maxSignatures, float64(m.totalSignatures)/float64(m.blocks))
+}
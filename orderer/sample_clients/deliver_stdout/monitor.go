@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"google.golang.org/grpc"
+)
+
+// maxRedialDelay bounds the delay between two reconnections in monitor mode.
+const maxRedialDelay = 30 * time.Second
+
+// alert reports in monitor mode a delivery which must not be resumed, such
+// as one of a block failing verification or of a malformed block.
+func (r *deliverClient) alert(reason string) {
+	fmt.Printf("ALERT: %s\n", reason)
+	r.alerted = true
+}
+
+// quorumAlert reports a block which failed verification in monitor mode,
+// along with the signers seen on earlier blocks but missing from it.
+func (r *deliverClient) quorumAlert(block *cb.Block) {
+	missing := "none known"
+	if len(r.verifier.missing) > 0 {
+		missing = strings.Join(r.verifier.missing, ", ")
+	}
+	r.alert(fmt.Sprintf("block %d of channel %s failed to reach quorum, missing signers: %s", block.Header.Number, r.channelID, missing))
+}
+
+// monitorUntilAlert delivers and verifies blocks, reconnecting with redial
+// whenever delivery is lost, until a block fails verification, a malformed or
+// unexpected genesis block is delivered, or the requested range has been
+// delivered. It returns the exit code of the client.
+func (r *deliverClient) monitorUntilAlert(redial func() (*grpc.ClientConn, ab.AtomicBroadcast_DeliverClient, error)) int {
+	for attempt := 1; ; attempt++ {
+		status := r.readUntilClose()
+		if r.verifier.unavailable {
+			return exitConfigError
+		}
+		if r.alerted {
+			return exitFailure
+		}
+		if status == cb.Status_SUCCESS {
+			return exitSuccess
+		}
+
+		delay := time.Duration(attempt) * time.Second
+		if delay > maxRedialDelay {
+			delay = maxRedialDelay
+		}
+		fmt.Printf("Delivery lost with status %s, reconnecting in %s\n", status, delay)
+		time.Sleep(delay)
+
+		conn, client, err := redial()
+		if err != nil {
+			fmt.Println("Error reconnecting:", err)
+			continue
+		}
+		r.close()
+		r.conn, r.client = conn, client
+		if err := r.resume(); err != nil {
+			fmt.Println("Received error:", err)
+			continue
+		}
+		attempt = 0
+	}
+}
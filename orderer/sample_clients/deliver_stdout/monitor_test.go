@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"io"
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// mockDeliverClient replays the given responses, then fails with io.EOF.
+type mockDeliverClient struct {
+	grpc.ClientStream
+	responses []*ab.DeliverResponse
+}
+
+func (m *mockDeliverClient) Send(*cb.Envelope) error {
+	return nil
+}
+
+func (m *mockDeliverClient) Recv() (*ab.DeliverResponse, error) {
+	if len(m.responses) == 0 {
+		return nil, io.EOF
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+func (m *mockDeliverClient) CloseSend() error {
+	return nil
+}
+
+func TestMonitorAlertsWithoutRedialing(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		block   *cb.Block
+		genesis []byte
+	}{
+		{name: "malformed block", block: &cb.Block{Data: &cb.BlockData{}}},
+		{name: "unexpected genesis block", block: &cb.Block{Header: &cb.BlockHeader{}, Data: &cb.BlockData{}}, genesis: []byte("expected")},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mockDeliverClient{responses: []*ab.DeliverResponse{{Type: &ab.DeliverResponse_Block{Block: test.block}}}}
+			r := newDeliverClient(client, "foo", nil, true)
+			r.monitor = true
+			r.genesis = test.genesis
+			r.verifier = &verifier{channelID: "foo", n: 1, f: 0, sample: 1, to: 10}
+
+			code := r.monitorUntilAlert(func() (*grpc.ClientConn, ab.AtomicBroadcast_DeliverClient, error) {
+				t.Fatal("redialed after an alert")
+				return nil, nil, nil
+			})
+			assert.Equal(t, exitFailure, code)
+			assert.True(t, r.alerted)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	ab "github.com/hyperledger/fabric/protos/orderer"
+)
+
+// positionFlags holds the flags selecting the range of blocks requested.
+//
+// The range is given either by the legacy -seek shortcut or by -start,
+// -stop and -follow, which cannot be combined with it. -start defaults to
+// the oldest block; without -stop, delivery ends at the newest block at the
+// time of the request unless -follow keeps waiting for new blocks. -tail
+// cannot be combined with them either but takes precedence over -seek, and
+// -checkpoint takes precedence over all of them.
+type positionFlags struct {
+	seek    int
+	seekSet bool
+	start   string
+	stop    string
+	follow  bool
+	tail    uint64
+}
+
+// explicit returns whether any flag selecting the range was set.
+func (p positionFlags) explicit() bool {
+	return p.seekSet || p.start != "" || p.stop != "" || p.follow || p.tail > 0
+}
+
+// parsePosition parses "oldest", "newest" or a block number.
+func parsePosition(value string) (*ab.SeekPosition, error) {
+	switch value {
+	case "oldest":
+		return oldest, nil
+	case "newest":
+		return newest, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("wrong position %q, oldest, newest or a block number expected", value)
+	}
+	return specified(number), nil
+}
+
+// seekPositions maps the legacy -seek values to the positions of a request.
+func seekPositions(seek int) (*ab.SeekPosition, *ab.SeekPosition, error) {
+	switch {
+	case seek == -2:
+		return oldest, maxStop, nil
+	case seek == -1:
+		return newest, maxStop, nil
+	case seek >= 0:
+		return specified(uint64(seek)), specified(uint64(seek)), nil
+	default:
+		return nil, nil, fmt.Errorf("wrong seek value %d", seek)
+	}
+}
+
+// resolve reconciles the flags into the start and stop positions of the
+// seek request, failing on contradictory combinations.
+func (p positionFlags) resolve(behavior ab.SeekInfo_SeekBehavior) (*ab.SeekPosition, *ab.SeekPosition, error) {
+	ranged := p.start != "" || p.stop != "" || p.follow
+	if !ranged {
+		return seekPositions(p.seek)
+	}
+	if p.seekSet || p.tail > 0 {
+		return nil, nil, errors.New("-seek and -tail cannot be combined with -start, -stop or -follow")
+	}
+	if p.follow && p.stop != "" {
+		return nil, nil, errors.New("-follow cannot be combined with -stop")
+	}
+	if p.follow && behavior == ab.SeekInfo_FAIL_IF_NOT_READY {
+		return nil, nil, errors.New("-follow requires the BLOCK_UNTIL_READY behavior")
+	}
+
+	start, stop := oldest, newest
+	var err error
+	if p.start != "" {
+		if start, err = parsePosition(p.start); err != nil {
+			return nil, nil, err
+		}
+	}
+	switch {
+	case p.follow:
+		stop = maxStop
+	case p.stop != "":
+		if stop, err = parsePosition(p.stop); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if start.GetSpecified() != nil && stop.GetSpecified() != nil && start.GetSpecified().Number > stop.GetSpecified().Number {
+		return nil, nil, fmt.Errorf("-start %d is after -stop %d", start.GetSpecified().Number, stop.GetSpecified().Number)
+	}
+	return start, stop, nil
+}
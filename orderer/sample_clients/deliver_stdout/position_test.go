@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePositions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		flags    positionFlags
+		behavior ab.SeekInfo_SeekBehavior
+		start    *ab.SeekPosition
+		stop     *ab.SeekPosition
+		err      string
+	}{
+		{name: "default", flags: positionFlags{seek: -2}, start: oldest, stop: maxStop},
+		{name: "legacy newest", flags: positionFlags{seek: -1, seekSet: true}, start: newest, stop: maxStop},
+		{name: "legacy single", flags: positionFlags{seek: 5, seekSet: true}, start: specified(5), stop: specified(5)},
+		{name: "legacy wrong", flags: positionFlags{seek: -3, seekSet: true}, err: "wrong seek value -3"},
+		{name: "range", flags: positionFlags{seek: -2, start: "3", stop: "7"}, start: specified(3), stop: specified(7)},
+		{name: "start only", flags: positionFlags{seek: -2, start: "3"}, start: specified(3), stop: newest},
+		{name: "stop only", flags: positionFlags{seek: -2, stop: "7"}, start: oldest, stop: specified(7)},
+		{name: "follow", flags: positionFlags{seek: -2, start: "newest", follow: true}, start: newest, stop: maxStop},
+		{name: "seek and start", flags: positionFlags{seek: 1, seekSet: true, start: "3"}, err: "-seek and -tail cannot be combined with -start, -stop or -follow"},
+		{name: "tail and stop", flags: positionFlags{seek: -2, tail: 2, stop: "3"}, err: "-seek and -tail cannot be combined with -start, -stop or -follow"},
+		{name: "follow and stop", flags: positionFlags{seek: -2, stop: "3", follow: true}, err: "-follow cannot be combined with -stop"},
+		{name: "follow without waiting", flags: positionFlags{seek: -2, follow: true}, behavior: ab.SeekInfo_FAIL_IF_NOT_READY, err: "-follow requires the BLOCK_UNTIL_READY behavior"},
+		{name: "reversed", flags: positionFlags{seek: -2, start: "7", stop: "3"}, err: "-start 7 is after -stop 3"},
+		{name: "wrong position", flags: positionFlags{seek: -2, start: "first"}, err: `wrong position "first", oldest, newest or a block number expected`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			start, stop, err := test.flags.resolve(test.behavior)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.start, start)
+			assert.Equal(t, test.stop, stop)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// verifyDataHash checks that the data hash recorded in the block header
+// matches the hash of the block data.
+func verifyDataHash(block *cb.Block, hash func([]byte) []byte) error {
+	if block.Data == nil {
+		return fmt.Errorf("block %d has no data", block.Header.Number)
+	}
+	if computed := hash(block.Data.Bytes()); !bytes.Equal(computed, block.Header.DataHash) {
+		return fmt.Errorf("block %d data hash is %x, header declares %x", block.Header.Number, computed, block.Header.DataHash)
+	}
+	return nil
+}
+
+// proveInclusion looks for the transaction with the given ID in the block
+// and, if found, prints its position along with the data hash of the header
+// and the data hash recomputed from the block data. As the block data hash
+// is a flat hash over the concatenation of all transactions, the
+// transactions of the block form the inclusion proof.
+func proveInclusion(block *cb.Block, txID string, hash func([]byte) []byte) bool {
+	if block.Data == nil {
+		return false
+	}
+	for i, data := range block.Data.Data {
+		env, err := utils.GetEnvelopeFromBlock(data)
+		if err != nil {
+			continue
+		}
+		chdr, err := utils.ChannelHeader(env)
+		if err != nil || chdr.TxId != txID {
+			continue
+		}
+
+		fmt.Printf("Transaction %s found in block %d at index %d of %d\n", txID, block.Header.Number, i, len(block.Data.Data))
+		fmt.Printf("  transaction hash:     %x\n", hash(data))
+		fmt.Printf("  header data hash:     %x\n", block.Header.DataHash)
+		fmt.Printf("  recomputed data hash: %x\n", hash(block.Data.Bytes()))
+		if err := verifyDataHash(block, hash); err != nil {
+			fmt.Printf("  inclusion NOT proven: %s\n", err)
+		} else {
+			fmt.Println("  inclusion proven: the recomputed data hash matches the header")
+		}
+		return true
+	}
+	return false
+}
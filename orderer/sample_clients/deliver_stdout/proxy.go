@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+)
+
+// proxyDialOption tunnels the connections to the RPC server through the
+// proxy at the URL, either socks5://[USER:PASSWORD@]HOST:PORT or
+// http://[USER:PASSWORD@]HOST:PORT for an HTTP CONNECT proxy. The proxy only
+// relays bytes, so that TLS, if enabled, is still negotiated with the RPC
+// server itself.
+func proxyDialOption(rawURL string) (grpc.DialOption, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("wrong proxy URL %s: %s", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "socks5":
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: password}
+		}
+		return grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			dialer, err := proxy.SOCKS5("tcp", u.Host, auth, &net.Dialer{Timeout: timeout})
+			if err != nil {
+				return nil, err
+			}
+			return dialer.Dial("tcp", addr)
+		}), nil
+	case "http":
+		return grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			conn, err := net.DialTimeout("tcp", u.Host, timeout)
+			if err != nil {
+				return nil, err
+			}
+			tunnel, err := httpConnect(conn, addr, u.User, timeout)
+			if err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("proxy %s: %s", u.Host, err)
+			}
+			return tunnel, nil
+		}), nil
+	default:
+		return nil, fmt.Errorf("wrong proxy URL %s: scheme must be socks5 or http", rawURL)
+	}
+}
+
+// httpConnect asks the HTTP proxy at the other end of conn to open a tunnel
+// to addr, and returns the tunnel.
+func httpConnect(conn net.Conn, addr string, user *url.Userinfo, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CONNECT to %s failed: %s", addr, resp.Status)
+	}
+	return &bufferedConn{Conn: conn, r: r}, nil
+}
+
+// bufferedConn reads through the reader which consumed the response of the
+// proxy, so that the bytes it buffered past the response are not lost.
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPConnect(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	requests := make(chan *http.Request, 1)
+	go func() {
+		defer server.Close()
+		req, err := http.ReadRequest(bufio.NewReader(server))
+		if err != nil {
+			close(requests)
+			return
+		}
+		requests <- req
+		// The first bytes of the tunnel arrive along with the response.
+		server.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nhello"))
+	}()
+
+	tunnel, err := httpConnect(client, "orderer:7050", url.UserPassword("user", "secret"), time.Second)
+	assert.NoError(t, err)
+	req := <-requests
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "orderer:7050", req.Host)
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", req.Header.Get("Proxy-Authorization"))
+
+	data, err := ioutil.ReadAll(tunnel)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestHTTPConnectRefused(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(server)); err == nil {
+			server.Write([]byte("HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n"))
+		}
+	}()
+
+	_, err := httpConnect(client, "orderer:7050", nil, time.Second)
+	assert.EqualError(t, err, "CONNECT to orderer:7050 failed: 403 Forbidden")
+}
+
+func TestProxyDialOptionWrongScheme(t *testing.T) {
+	_, err := proxyDialOption("ftp://proxy:21")
+	assert.EqualError(t, err, "wrong proxy URL ftp://proxy:21: scheme must be socks5 or http")
+}
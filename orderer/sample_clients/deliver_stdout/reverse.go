@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// verifyHashChain checks that the block links to its predecessor.
+func verifyHashChain(prev *cb.Block, block *cb.Block, hash func([]byte) []byte) error {
+	if prevHash := hash(prev.Header.Bytes()); !bytes.Equal(block.Header.PreviousHash, prevHash) {
+		return fmt.Errorf("block %d previous hash is %x, block %d hash is %x", block.Header.Number, block.Header.PreviousHash, prev.Header.Number, prevHash)
+	}
+	return nil
+}
+
+// verifyReverse checks the blocks of the channel from the newest one down to
+// the floor, one block at a time. Every block is checked against its data
+// hash, its signatures if a verifier is set, and the hash of its predecessor,
+// which is fetched next. It returns whether all the blocks passed.
+func (r *deliverClient) verifyReverse(floor uint64) bool {
+	block, err := r.fetchBlock(newest)
+	if err != nil {
+		fmt.Println("Error fetching newest block:", err)
+		return false
+	}
+	if floor > block.Header.Number {
+		fmt.Printf("Floor %d is above the newest block %d\n", floor, block.Header.Number)
+		return false
+	}
+
+	total := block.Header.Number - floor + 1
+	var checked, failed uint64
+	for {
+		number := block.Header.Number
+		ok := true
+		if err := verifyDataHash(block, r.hash); err != nil {
+			fmt.Println(err)
+			ok = false
+		}
+		if r.verifier != nil && r.verifier.shouldVerify(number) && !r.verifier.verify(block) {
+			if r.verifier.unavailable {
+				fmt.Println("Aborting verification, no MSP is available to verify signatures")
+				return false
+			}
+			ok = false
+		}
+		if number == 0 && !r.checkGenesis(block) {
+			ok = false
+		}
+
+		var prev *cb.Block
+		if number > floor {
+			prev, err = r.fetchBlock(specified(number - 1))
+			if err != nil {
+				fmt.Printf("Error fetching block %d: %s\n", number-1, err)
+				return false
+			}
+			if err := verifyHashChain(prev, block, r.hash); err != nil {
+				fmt.Println(err)
+				ok = false
+			}
+		}
+
+		checked++
+		if !ok {
+			failed++
+		}
+		fmt.Printf("Block %d: %s (%d of %d checked)\n", number, passOrFail(ok), checked, total)
+
+		if prev == nil {
+			break
+		}
+		block = prev
+	}
+
+	fmt.Printf("Checked %d blocks from %d down to %d, %d failed\n", checked, floor+total-1, floor, failed)
+	return failed == 0
+}
+
+func passOrFail(ok bool) string {
+	if ok {
+		return "pass"
+	}
+	return "FAIL"
+}
@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// tipPollInterval is the delay between two probes of the newest block while
+// waiting for the expected tip.
+const tipPollInterval = time.Second
+
+// checkTip probes the newest block of the channel until its number reaches
+// the expected one, if any, or the timeout expires. It prints the observed
+// tip and returns whether its number and header hash match the expected
+// ones; a nil hash is not checked.
+func (r *deliverClient) checkTip(number *uint64, hash []byte, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	var block *cb.Block
+	for {
+		var err error
+		block, err = r.fetchBlock(newest)
+		if err != nil {
+			fmt.Println("Error fetching the newest block:", err)
+			return false
+		}
+		if number == nil || block.Header.Number >= *number || !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(tipPollInterval)
+	}
+
+	computed := r.hash(block.Header.Bytes())
+	fmt.Printf("Tip of channel %s: block %d, hash %x\n", r.channelID, block.Header.Number, computed)
+
+	ok := true
+	if number != nil && block.Header.Number != *number {
+		fmt.Printf("Tip is block %d, %d expected\n", block.Header.Number, *number)
+		ok = false
+	}
+	if hash != nil && !bytes.Equal(computed, hash) {
+		fmt.Printf("Tip hash does not match the expected %x\n", hash)
+		ok = false
+	}
+	return ok
+}
@@ -0,0 +1,303 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/msp"
+	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
+	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// localMSP returns the local MSP, it is replaced in tests.
+var localMSP = mspmgmt.GetLocalMSP
+
+// verifier checks the orderer signatures attached to the metadata of
+// delivered blocks. Only the blocks selected by the sampling rate and the
+// [from, to] range are verified, every other block is merely received.
+type verifier struct {
+	channelID string
+	n         int
+	f         int
+	sample    uint64
+	from      uint64
+	to        uint64
+
+	// strict fails a block on any invalid signature, even if a quorum of
+	// valid signatures remains.
+	strict bool
+
+	// bundle holds the latest channel config seen, whose MSPs resolve the
+	// identities of the signers.
+	bundle      *channelconfig.Bundle
+	warned      bool
+	unavailable bool
+
+	verified uint64
+	failed   uint64
+
+	// known maps every signer seen on a valid signature to its description,
+	// missing lists the known signers absent from the last block verified.
+	known   map[string]string
+	missing []string
+}
+
+// shouldVerify returns whether the block with the given number is selected
+// for verification; a zero sampling rate disables verification altogether.
+func (v *verifier) shouldVerify(number uint64) bool {
+	if v.sample == 0 || number < v.from || number > v.to {
+		return false
+	}
+	return (number-v.from)%v.sample == 0
+}
+
+// selects returns whether the block is to be verified. Config blocks are
+// always selected, since their MSPs are only loaded once they pass.
+func (v *verifier) selects(block *cb.Block) bool {
+	return v.shouldVerify(block.Header.Number) || utils.IsConfigBlock(block)
+}
+
+// verifyAndUpdate verifies the block and, if it passes, loads the MSPs of a
+// config block for the blocks following it. A config block is thus checked
+// against the config in force before it, never against the MSPs it carries.
+func (v *verifier) verifyAndUpdate(block *cb.Block) bool {
+	if !v.verify(block) {
+		if utils.IsConfigBlock(block) {
+			fmt.Printf("Ignoring the channel config of block %d, which failed verification\n", block.Header.Number)
+		}
+		return false
+	}
+	v.updateConfig(block)
+	return true
+}
+
+// updateConfig refreshes the channel MSPs if the block is a config block.
+func (v *verifier) updateConfig(block *cb.Block) {
+	if !utils.IsConfigBlock(block) {
+		return
+	}
+	env, err := utils.ExtractEnvelope(block, 0)
+	if err != nil {
+		fmt.Printf("Error extracting config envelope from block %d: %s\n", block.Header.Number, err)
+		return
+	}
+	bundle, err := channelconfig.NewBundleFromEnvelope(env)
+	if err != nil {
+		fmt.Printf("Error loading channel config from block %d: %s\n", block.Header.Number, err)
+		return
+	}
+	v.bundle = bundle
+}
+
+// deriveQuorum sets n to the number of orderer addresses of the channel
+// config and f to the largest number of faults n orderers tolerate, unless
+// they were set explicitly. The flag values are kept if no config block of
+// the channel has been loaded.
+func (v *verifier) deriveQuorum(nSet bool, fSet bool) {
+	if v.bundle == nil {
+		fmt.Printf("No channel config loaded, using n=%d and f=%d\n", v.n, v.f)
+		return
+	}
+	addresses := v.bundle.ChannelConfig().OrdererAddresses()
+	if len(addresses) == 0 {
+		fmt.Printf("No orderer addresses in the channel config, using n=%d and f=%d\n", v.n, v.f)
+		return
+	}
+	if !nSet {
+		v.n = len(addresses)
+	}
+	if !fSet {
+		v.f = (v.n - 1) / 3
+	}
+	fmt.Printf("Derived n=%d and f=%d from the %d orderer addresses of channel %s\n", v.n, v.f, len(addresses), v.channelID)
+}
+
+// deserializer returns the MSPs of the channel, or the local MSP as long as
+// no config block of the channel has been seen. It fails, marking the
+// verifier unavailable, if the local MSP is not usable: it was never set
+// up, or its own signing identity does not validate against its roots.
+func (v *verifier) deserializer() (msp.IdentityDeserializer, error) {
+	if v.bundle != nil {
+		return v.bundle.MSPManager(), nil
+	}
+	if !v.warned {
+		fmt.Printf("No config block seen yet for channel %s, resolving signers with the local MSP\n", v.channelID)
+		v.warned = true
+	}
+	local := localMSP()
+	signer, err := local.GetDefaultSigningIdentity()
+	if err == nil {
+		err = local.Validate(signer.GetPublicVersion())
+	}
+	if err != nil {
+		v.unavailable = true
+		return nil, fmt.Errorf("no MSP loaded for channel %s; cannot verify signatures: %s", v.channelID, err)
+	}
+	return local, nil
+}
+
+// verify validates the signatures of the block and keeps track of the
+// outcome, returning whether the block passed.
+func (v *verifier) verify(block *cb.Block) bool {
+	err := v.validateSignatures(block)
+	v.verified++
+	if err != nil {
+		v.failed++
+		fmt.Printf("Block %d failed verification: %s\n", block.Header.Number, err)
+		return false
+	}
+	fmt.Printf("Block %d verified\n", block.Header.Number)
+	return true
+}
+
+// validateSignatures requires f+1 distinct orderers to have validly signed
+// both the SIGNATURES and the LAST_CONFIG metadata of the block, and in
+// strict mode no invalid signature. Missing or malformed metadata fails the
+// block.
+func (v *verifier) validateSignatures(block *cb.Block) error {
+	if block.Header.Number == 0 {
+		fmt.Println("Block 0 requires no signature validation")
+		return nil
+	}
+
+	indexes := []cb.BlockMetadataIndex{cb.BlockMetadataIndex_SIGNATURES, cb.BlockMetadataIndex_LAST_CONFIG}
+	metas := make([]*cb.Metadata, len(indexes))
+	for i, index := range indexes {
+		meta, err := blockMetadata(block, index)
+		if err != nil {
+			return err
+		}
+		metas[i] = meta
+	}
+
+	des, err := v.deserializer()
+	if err != nil {
+		return err
+	}
+	v.missing = nil
+	for j, index := range indexes {
+		meta := metas[j]
+		signers := make(map[string]struct{})
+		invalid := 0
+		for i, sig := range meta.Signatures {
+			shdr, err := utils.GetSignatureHeader(sig.SignatureHeader)
+			if err != nil {
+				fmt.Printf("  %s signature %d: error unmarshaling signature header: %s\n", index, i, err)
+				invalid++
+				continue
+			}
+			identity, err := des.DeserializeIdentity(shdr.Creator)
+			if err != nil {
+				fmt.Printf("  %s signature %d: error deserializing signer: %s\n", index, i, err)
+				invalid++
+				continue
+			}
+			err = identity.Verify(util.ConcatenateBytes(meta.Value, sig.SignatureHeader, block.Header.Bytes()), sig.Signature)
+			if err != nil {
+				fmt.Printf("  %s signature %d: invalid signature %x: %s\n", index, i, sig.Signature, err)
+				invalid++
+				continue
+			}
+			signers[string(shdr.Creator)] = struct{}{}
+		}
+		fmt.Printf("  %s: %d valid signers, %d invalid signatures\n", index, len(signers), invalid)
+		v.trackSigners(signers)
+
+		if v.strict && invalid > 0 {
+			return fmt.Errorf("%s metadata carries %d invalid signatures", index, invalid)
+		}
+		if len(signers) < v.f+1 {
+			return fmt.Errorf("%s metadata is signed by %d of %d orderers, %d required", index, len(signers), v.n, v.f+1)
+		}
+	}
+	return nil
+}
+
+// trackSigners records the signers of a metadata index as known and adds the
+// known signers it lacks to the missing ones.
+func (v *verifier) trackSigners(signers map[string]struct{}) {
+	if v.known == nil {
+		v.known = make(map[string]string)
+	}
+	for creator := range signers {
+		if _, ok := v.known[creator]; !ok {
+			v.known[creator] = describeSigner([]byte(creator))
+		}
+	}
+	for creator, description := range v.known {
+		if _, ok := signers[creator]; ok {
+			continue
+		}
+		found := false
+		for _, m := range v.missing {
+			found = found || m == description
+		}
+		if !found {
+			v.missing = append(v.missing, description)
+		}
+	}
+	sort.Strings(v.missing)
+}
+
+// describeSigner returns the MSP ID and certificate subject of a serialized
+// identity.
+func describeSigner(creator []byte) string {
+	sid := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(creator, sid); err != nil {
+		return fmt.Sprintf("%x", creator)
+	}
+	block, _ := pem.Decode(sid.IdBytes)
+	if block == nil {
+		return sid.Mspid
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return sid.Mspid
+	}
+	return fmt.Sprintf("%s (%s)", sid.Mspid, cert.Subject.CommonName)
+}
+
+// dumpSignatures prints, for every signature of the SIGNATURES and
+// LAST_CONFIG metadata, the exact payload the signer is expected to have
+// signed, the signature and the signer, without verifying anything.
+func dumpSignatures(block *cb.Block) {
+	fmt.Printf("Block %d header: %x\n", block.Header.Number, block.Header.Bytes())
+	for _, index := range []cb.BlockMetadataIndex{cb.BlockMetadataIndex_SIGNATURES, cb.BlockMetadataIndex_LAST_CONFIG} {
+		meta, err := blockMetadata(block, index)
+		if err != nil {
+			fmt.Printf("  %s: %s\n", index, err)
+			continue
+		}
+
+		fmt.Printf("  %s value: %x\n", index, meta.Value)
+		for i, sig := range meta.Signatures {
+			fmt.Printf("  %s signature %d:\n", index, i)
+			fmt.Printf("    signed payload: %x\n", util.ConcatenateBytes(meta.Value, sig.SignatureHeader, block.Header.Bytes()))
+			fmt.Printf("    signature: %x\n", sig.Signature)
+			shdr, err := utils.GetSignatureHeader(sig.SignatureHeader)
+			if err != nil {
+				fmt.Printf("    error unmarshaling signature header: %s\n", err)
+				continue
+			}
+			sid := &mspprotos.SerializedIdentity{}
+			if err := proto.Unmarshal(shdr.Creator, sid); err != nil {
+				fmt.Printf("    error unmarshaling signer: %s\n", err)
+				continue
+			}
+			fmt.Printf("    signer: %s\n%s", sid.Mspid, sid.IdBytes)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/msp"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifierWithoutMSP(t *testing.T) {
+	defer func(f func() msp.MSP) { localMSP = f }(localMSP)
+	unset, err := msp.New(&msp.BCCSPNewOpts{NewBaseOpts: msp.NewBaseOpts{Version: msp.MSPv1_0}})
+	assert.NoError(t, err)
+	localMSP = func() msp.MSP { return unset }
+
+	v := &verifier{channelID: "foo", n: 4, f: 1, sample: 1, to: 10}
+	block := &cb.Block{
+		Header:   &cb.BlockHeader{Number: 1},
+		Data:     &cb.BlockData{},
+		Metadata: &cb.BlockMetadata{Metadata: [][]byte{utils.MarshalOrPanic(&cb.Metadata{}), {}, {}, {}}},
+	}
+	assert.True(t, v.shouldVerify(1))
+	assert.NotPanics(t, func() { assert.False(t, v.verify(block)) })
+	assert.True(t, v.unavailable)
+	assert.Equal(t, uint64(1), v.failed)
+}
+
+func TestVerifierWithLocalMSP(t *testing.T) {
+	v := &verifier{channelID: "foo", n: 4, f: 1, sample: 1, to: 10}
+	des, err := v.deserializer()
+	assert.NoError(t, err)
+	assert.NotNil(t, des)
+	assert.False(t, v.unavailable)
+}
+
+func TestVerifyAndUpdateConfig(t *testing.T) {
+	conf := genesisconfig.Load(genesisconfig.SampleSingleMSPSoloProfile)
+
+	// A config block carrying no valid signatures must not get its MSPs
+	// loaded, even outside the sampling rate.
+	forged := encoder.New(conf).GenesisBlockForChannel("foo")
+	forged.Header.Number = 5
+	v := &verifier{channelID: "foo", n: 1, f: 0, sample: 2, to: 10}
+	assert.False(t, v.shouldVerify(5))
+	assert.True(t, v.selects(forged))
+	assert.False(t, v.verifyAndUpdate(forged))
+	assert.Nil(t, v.bundle)
+
+	genesis := encoder.New(conf).GenesisBlockForChannel("foo")
+	assert.True(t, v.verifyAndUpdate(genesis))
+	assert.NotNil(t, v.bundle)
+}
+
+func TestShouldVerify(t *testing.T) {
+	v := &verifier{channelID: "foo", sample: 2, from: 3, to: 7}
+	for number, expected := range map[uint64]bool{0: false, 3: true, 4: false, 5: true, 7: true, 9: false} {
+		assert.Equal(t, expected, v.shouldVerify(number), "block %d", number)
+	}
+}
+
+func TestVerifyMalformedMetadata(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		metadata *cb.BlockMetadata
+		err      string
+	}{
+		{name: "nil", err: "block has no metadata"},
+		{name: "empty", metadata: &cb.BlockMetadata{}, err: "block has 0 metadata indexes, SIGNATURES missing"},
+		{name: "truncated", metadata: &cb.BlockMetadata{Metadata: [][]byte{utils.MarshalOrPanic(&cb.Metadata{})}}, err: "block has 1 metadata indexes, LAST_CONFIG missing"},
+		{name: "garbage", metadata: &cb.BlockMetadata{Metadata: [][]byte{[]byte("garbage")}}, err: "error unmarshaling SIGNATURES metadata"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			block := &cb.Block{Header: &cb.BlockHeader{Number: 1}, Data: &cb.BlockData{}, Metadata: test.metadata}
+
+			v := &verifier{channelID: "foo", n: 1, f: 0, sample: 1, to: 10}
+			err := v.validateSignatures(block)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+			assert.NotPanics(t, func() { assert.False(t, v.verify(block)) })
+			assert.NotPanics(t, func() { dumpSignatures(block) })
+			assert.NotPanics(t, func() { newMetadataSizes().record(block) })
+			assert.NotPanics(t, func() { assert.False(t, newLastConfigChecker().check(block)) })
+		})
+	}
+}
+
+func TestCheckBlock(t *testing.T) {
+	assert.EqualError(t, checkBlock(nil), "empty block")
+	assert.EqualError(t, checkBlock(&cb.Block{Data: &cb.BlockData{}}), "block has no header")
+	assert.EqualError(t, checkBlock(&cb.Block{Header: &cb.BlockHeader{Number: 3}}), "block 3 has no data")
+	assert.NoError(t, checkBlock(&cb.Block{Header: &cb.BlockHeader{Number: 3}, Data: &cb.BlockData{}}))
+}
@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package submit provides a "submit and wait for commit" primitive on top of
+// the Broadcast and Deliver services of the ordering service, meant for
+// tests and tooling.
+package submit
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// DialOptions returns the gRPC dial options matching the TLS flags of the
+// sample clients.
+func DialOptions(tlsEnabled bool, caFile string, serverName string) ([]grpc.DialOption, error) {
+	if !tlsEnabled {
+		return []grpc.DialOption{grpc.WithInsecure()}, nil
+	}
+	creds, err := credentials.NewClientTLSFromFile(caFile, serverName)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS root certificate %s: %s", caFile, err)
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(creds)}, nil
+}
+
+// SubmitAndWait broadcasts env on the given channel and waits, up to timeout,
+// for the block containing it to be delivered. It returns the number of that
+// block and the validation code recorded for the transaction in the
+// TRANSACTIONS_FILTER metadata, or VALID if none was recorded, as the
+// ordering service does not validate transactions itself. The signer is used
+// to sign the Deliver requests.
+func SubmitAndWait(conn *grpc.ClientConn, signer crypto.LocalSigner, channelID string, env *cb.Envelope, timeout time.Duration) (uint64, pb.TxValidationCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	envBytes, err := utils.Marshal(env)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	client := ab.NewAtomicBroadcastClient(conn)
+	deliver, err := client.Deliver(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer deliver.CloseSend()
+
+	// The block containing the envelope can only be cut after the newest
+	// block at the time of the submission.
+	height, err := newestBlockNumber(deliver, signer, channelID)
+	if err != nil {
+		return 0, 0, wrapTimeout(ctx, err)
+	}
+
+	broadcast, err := client.Broadcast(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer broadcast.CloseSend()
+	if err := broadcast.Send(env); err != nil {
+		return 0, 0, wrapTimeout(ctx, err)
+	}
+	resp, err := broadcast.Recv()
+	if err != nil {
+		return 0, 0, wrapTimeout(ctx, err)
+	}
+	if resp.Status != cb.Status_SUCCESS {
+		return 0, 0, fmt.Errorf("broadcast failed with status %s: %s", resp.Status, resp.Info)
+	}
+
+	if err := deliver.Send(seekEnvelope(signer, channelID, specified(height+1), specified(math.MaxUint64))); err != nil {
+		return 0, 0, wrapTimeout(ctx, err)
+	}
+	for {
+		msg, err := deliver.Recv()
+		if err != nil {
+			return 0, 0, wrapTimeout(ctx, err)
+		}
+		switch t := msg.Type.(type) {
+		case *ab.DeliverResponse_Status:
+			return 0, 0, fmt.Errorf("deliver ended with status %s before the envelope was committed", t.Status)
+		case *ab.DeliverResponse_Block:
+			for i, data := range t.Block.Data.Data {
+				if bytes.Equal(data, envBytes) {
+					return t.Block.Header.Number, validationCode(t.Block, i), nil
+				}
+			}
+		}
+	}
+}
+
+func newestBlockNumber(deliver ab.AtomicBroadcast_DeliverClient, signer crypto.LocalSigner, channelID string) (uint64, error) {
+	newest := &ab.SeekPosition{Type: &ab.SeekPosition_Newest{Newest: &ab.SeekNewest{}}}
+	if err := deliver.Send(seekEnvelope(signer, channelID, newest, newest)); err != nil {
+		return 0, err
+	}
+
+	var block *cb.Block
+	for {
+		msg, err := deliver.Recv()
+		if err != nil {
+			return 0, err
+		}
+		switch t := msg.Type.(type) {
+		case *ab.DeliverResponse_Block:
+			block = t.Block
+		case *ab.DeliverResponse_Status:
+			if t.Status != cb.Status_SUCCESS || block == nil {
+				return 0, fmt.Errorf("fetching newest block failed with status %s", t.Status)
+			}
+			return block.Header.Number, nil
+		}
+	}
+}
+
+func seekEnvelope(signer crypto.LocalSigner, channelID string, start *ab.SeekPosition, stop *ab.SeekPosition) *cb.Envelope {
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_DELIVER_SEEK_INFO, channelID, signer, &ab.SeekInfo{
+		Start:    start,
+		Stop:     stop,
+		Behavior: ab.SeekInfo_BLOCK_UNTIL_READY,
+	}, 0, 0)
+	if err != nil {
+		panic(err)
+	}
+	return env
+}
+
+func specified(number uint64) *ab.SeekPosition {
+	return &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: number}}}
+}
+
+func validationCode(block *cb.Block, index int) pb.TxValidationCode {
+	if block.Metadata == nil || len(block.Metadata.Metadata) <= int(cb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		return pb.TxValidationCode_VALID
+	}
+	filter := block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	if index >= len(filter) {
+		return pb.TxValidationCode_VALID
+	}
+	return pb.TxValidationCode(filter[index])
+}
+
+func wrapTimeout(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out waiting for the envelope to be committed: %s", err)
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package submit
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// mockOrderer cuts one block per broadcast envelope, unless cut is false.
+type mockOrderer struct {
+	status cb.Status
+	cut    bool
+
+	mutex  sync.Mutex
+	blocks []*cb.Block
+	added  chan struct{}
+}
+
+func newMockOrderer(status cb.Status, cut bool) *mockOrderer {
+	genesis := &cb.Block{Header: &cb.BlockHeader{Number: 0}, Data: &cb.BlockData{}}
+	return &mockOrderer{status: status, cut: cut, blocks: []*cb.Block{genesis}, added: make(chan struct{}, 10)}
+}
+
+func (o *mockOrderer) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+	env, err := srv.Recv()
+	if err != nil {
+		return nil
+	}
+	if o.status == cb.Status_SUCCESS && o.cut {
+		o.mutex.Lock()
+		o.blocks = append(o.blocks, &cb.Block{
+			Header:   &cb.BlockHeader{Number: uint64(len(o.blocks))},
+			Data:     &cb.BlockData{Data: [][]byte{[]byte("other"), utils.MarshalOrPanic(env)}},
+			Metadata: &cb.BlockMetadata{Metadata: [][]byte{{}, {}, {0, byte(pb.TxValidationCode_MVCC_READ_CONFLICT)}}},
+		})
+		o.mutex.Unlock()
+		o.added <- struct{}{}
+	}
+	return srv.Send(&ab.BroadcastResponse{Status: o.status})
+}
+
+func (o *mockOrderer) Deliver(srv ab.AtomicBroadcast_DeliverServer) error {
+	for {
+		env, err := srv.Recv()
+		if err != nil {
+			return nil
+		}
+		payload, _ := utils.UnmarshalPayload(env.Payload)
+		seekInfo := &ab.SeekInfo{}
+		proto.Unmarshal(payload.Data, seekInfo)
+
+		if seekInfo.Start.GetNewest() != nil {
+			o.mutex.Lock()
+			newest := o.blocks[len(o.blocks)-1]
+			o.mutex.Unlock()
+			srv.Send(&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: newest}})
+			srv.Send(&ab.DeliverResponse{Type: &ab.DeliverResponse_Status{Status: cb.Status_SUCCESS}})
+			continue
+		}
+
+		next := seekInfo.Start.GetSpecified().Number
+		for {
+			o.mutex.Lock()
+			available := o.blocks[next:]
+			o.mutex.Unlock()
+			for _, block := range available {
+				srv.Send(&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: block}})
+				next++
+			}
+			select {
+			case <-o.added:
+			case <-srv.Context().Done():
+				return nil
+			}
+		}
+	}
+}
+
+func startMockOrderer(t *testing.T, o *mockOrderer) (*grpc.ClientConn, func()) {
+	lsnr, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	srv := grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(srv, o)
+	go srv.Serve(lsnr)
+
+	conn, err := grpc.Dial(lsnr.Addr().String(), grpc.WithInsecure())
+	assert.NoError(t, err)
+	return conn, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func testEnvelope() *cb.Envelope {
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_MESSAGE, "testchannel", mockcrypto.FakeLocalSigner, &cb.ConfigValue{Value: []byte("tx")}, 0, 0)
+	if err != nil {
+		panic(err)
+	}
+	return env
+}
+
+func TestSubmitAndWait(t *testing.T) {
+	conn, stop := startMockOrderer(t, newMockOrderer(cb.Status_SUCCESS, true))
+	defer stop()
+
+	number, code, err := SubmitAndWait(conn, mockcrypto.FakeLocalSigner, "testchannel", testEnvelope(), 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), number)
+	assert.Equal(t, pb.TxValidationCode_MVCC_READ_CONFLICT, code)
+}
+
+func TestSubmitAndWaitRejected(t *testing.T) {
+	conn, stop := startMockOrderer(t, newMockOrderer(cb.Status_BAD_REQUEST, true))
+	defer stop()
+
+	_, _, err := SubmitAndWait(conn, mockcrypto.FakeLocalSigner, "testchannel", testEnvelope(), 5*time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "BAD_REQUEST")
+}
+
+func TestSubmitAndWaitTimeout(t *testing.T) {
+	conn, stop := startMockOrderer(t, newMockOrderer(cb.Status_SUCCESS, false))
+	defer stop()
+
+	_, _, err := SubmitAndWait(conn, mockcrypto.FakeLocalSigner, "testchannel", testEnvelope(), 500*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}